@@ -0,0 +1,292 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/ericbrisrubio/go-workers-multipool/manager"
+	"github.com/ericbrisrubio/go-workers-multipool/pool"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//Format is the syntax of a config file
+type Format string
+
+const (
+	YAML Format = "yaml"
+	JSON Format = "json"
+	TOML Format = "toml"
+)
+
+//FormatOf returns the format of the file at path from its extension
+func FormatOf(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return YAML, nil
+	case ".json":
+		return JSON, nil
+	case ".toml":
+		return TOML, nil
+	}
+	return "", errors.New(fmt.Sprintf("unknown config format of `%s`, the extension has to be .yaml, .yml, .json or .toml", path))
+}
+
+//Config describes the pools of a manager
+type Config struct {
+	Pools []Pool
+}
+
+//Pool describes a pool and the worker function bound to it
+type Pool struct {
+	//ID is the id of the pool, key id
+	ID string
+	//InitialWorkers is the amount of workers started with the pool, key initial_workers
+	InitialWorkers int
+	//MaxJobsInQueue is the maximum amount of tasks waiting to be processed, key max_jobs_in_queue
+	MaxJobsInQueue int
+	//Verbose enables the logs of the underlying pool, key verbose
+	Verbose bool
+	//Backend is the name of the pool.Backend of the pool, key backend
+	Backend string
+	//Function is the name the worker function was registered with, key function
+	Function string
+	//Start tells whether the pool is started once built, key start (true if not set)
+	Start bool
+	//Timeout bounds how long the worker function can run for each task, key timeout
+	Timeout time.Duration
+	//DeadLetterQueue is the dead-letter queue of the terminally failed tasks, key dead_letter_queue
+	DeadLetterQueue string
+	//Retry defines how failed tasks are retried, key retry
+	Retry *Retry
+	//RateLimit bounds how many tasks start per second, key rate_limit with the keys rate and burst
+	RateLimit *manager.RateLimit
+}
+
+//Backoff kinds of a retry policy
+const (
+	ConstantBackoff    = "constant"
+	ExponentialBackoff = "exponential"
+)
+
+//Retry describes the retry policy of a pool
+type Retry struct {
+	//MaxAttempts is the maximum amount of times a task is run, key max_attempts
+	MaxAttempts int
+	//Backoff is the kind of backoff, constant or exponential, key backoff (no delay if not set)
+	Backoff string
+	//Delay is the delay of a constant backoff or the first one of an exponential backoff, key delay
+	Delay time.Duration
+	//MaxDelay bounds the delays of an exponential backoff, key max_delay
+	MaxDelay time.Duration
+	//Multiplier grows the delays of an exponential backoff, key multiplier (2 if not set)
+	Multiplier float64
+	//Jitter randomizes the delays by up to this fraction of their value, key jitter
+	Jitter float64
+}
+
+//Load reads the config file at path, its format is given by its extension
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseFile(path, data)
+}
+
+//parseFile parses data, the content of the config file at path
+func parseFile(path string, data []byte) (*Config, error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := Parse(data, format)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("config `%s`", path))
+	}
+	return config, nil
+}
+
+//Parse decodes and validates a config written in format. A key missing or invalid is reported by a *KeyError
+func Parse(data []byte, format Format) (*Config, error) {
+	document := map[string]interface{}{}
+	var err error
+	switch format {
+	case YAML:
+		err = yaml.Unmarshal(data, &document)
+	case JSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&document)
+	case TOML:
+		err = toml.Unmarshal(data, &document)
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown config format `%s`", format))
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("parsing %s", format))
+	}
+	return decode(document)
+}
+
+//decode builds the config from the document of a file, validating it
+func decode(document map[string]interface{}) (*Config, error) {
+	var err error
+	root := newObject("", document, &err)
+	config := &Config{}
+	ids := make(map[string]bool)
+	if root.require("pools") {
+		for _, item := range root.list("pools") {
+			definition := decodePool(item)
+			if definition.ID != "" && ids[definition.ID] {
+				item.fail("id", fmt.Sprintf("duplicated pool id `%s`", definition.ID))
+			}
+			ids[definition.ID] = true
+			config.Pools = append(config.Pools, definition)
+		}
+	}
+	root.done()
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func decodePool(item *object) Pool {
+	definition := Pool{
+		ID:              item.string("id"),
+		InitialWorkers:  item.int("initial_workers"),
+		MaxJobsInQueue:  item.int("max_jobs_in_queue"),
+		Verbose:         item.bool("verbose", false),
+		Backend:         item.string("backend"),
+		Function:        item.string("function"),
+		Start:           item.bool("start", true),
+		Timeout:         item.duration("timeout"),
+		DeadLetterQueue: item.string("dead_letter_queue"),
+	}
+	if item.require("id") && strings.TrimSpace(definition.ID) == "" {
+		item.fail("id", "cannot be empty")
+	}
+	if definition.InitialWorkers < 0 {
+		item.fail("initial_workers", "has to be greater or equal to 0")
+	}
+	if item.require("max_jobs_in_queue") && definition.MaxJobsInQueue < 1 {
+		item.fail("max_jobs_in_queue", "has to be greater than 0")
+	}
+	if definition.Backend != "" {
+		if _, err := pool.LookupBackend(definition.Backend); err != nil {
+			item.fail("backend", err.Error())
+		}
+	}
+	if item.require("function") {
+		if _, err := lookupFunc(definition.Function); err != nil {
+			item.fail("function", err.Error())
+		}
+	}
+	if definition.Timeout < 0 {
+		item.fail("timeout", "has to be greater or equal to 0")
+	}
+	if retry := item.object("retry"); retry != nil {
+		definition.Retry = decodeRetry(retry)
+	}
+	if limit := item.object("rate_limit"); limit != nil {
+		definition.RateLimit = &manager.RateLimit{Rate: limit.float("rate"), Burst: limit.int("burst")}
+		if limit.require("rate") && definition.RateLimit.Rate <= 0 {
+			limit.fail("rate", "has to be greater than 0")
+		}
+		if definition.RateLimit.Burst < 0 {
+			limit.fail("burst", "has to be greater or equal to 0")
+		}
+		limit.done()
+	}
+	item.done()
+	return definition
+}
+
+func decodeRetry(item *object) *Retry {
+	retry := &Retry{
+		MaxAttempts: item.int("max_attempts"),
+		Backoff:     item.string("backoff"),
+		Delay:       item.duration("delay"),
+		MaxDelay:    item.duration("max_delay"),
+		Multiplier:  item.float("multiplier"),
+		Jitter:      item.float("jitter"),
+	}
+	if item.require("max_attempts") && retry.MaxAttempts < 1 {
+		item.fail("max_attempts", "has to be greater than 0")
+	}
+	switch retry.Backoff {
+	case "":
+		for _, name := range []string{"delay", "max_delay", "multiplier", "jitter"} {
+			if item.has(name) {
+				item.fail(name, "requires a backoff")
+			}
+		}
+	case ConstantBackoff:
+		item.require("delay")
+		for _, name := range []string{"max_delay", "multiplier"} {
+			if item.has(name) {
+				item.fail(name, "only applies to an exponential backoff")
+			}
+		}
+	case ExponentialBackoff:
+		item.require("delay")
+		if item.require("max_delay") && retry.MaxDelay < retry.Delay {
+			item.fail("max_delay", "has to be greater or equal to delay")
+		}
+		if item.has("multiplier") && retry.Multiplier < 1 {
+			item.fail("multiplier", "has to be greater or equal to 1")
+		}
+	default:
+		item.fail("backoff", fmt.Sprintf("expected %s or %s, got %q", ConstantBackoff, ExponentialBackoff, retry.Backoff))
+	}
+	if retry.Delay < 0 {
+		item.fail("delay", "has to be greater or equal to 0")
+	}
+	if retry.Jitter < 0 || retry.Jitter > 1 {
+		item.fail("jitter", "has to be between 0 and 1")
+	}
+	item.done()
+	return retry
+}
+
+//Options returns the manager options of the pool
+func (definition Pool) Options() manager.PoolOptions {
+	options := manager.PoolOptions{
+		InitialWorkers:  definition.InitialWorkers,
+		MaxJobsInQueue:  definition.MaxJobsInQueue,
+		Verbose:         definition.Verbose,
+		Backend:         definition.Backend,
+		Timeout:         definition.Timeout,
+		DeadLetterQueue: definition.DeadLetterQueue,
+		Retry:           definition.Retry.policy(),
+	}
+	if definition.RateLimit != nil {
+		limit := *definition.RateLimit
+		options.RateLimit = &limit
+	}
+	return options
+}
+
+//policy returns the manager retry policy described by retry, nil if retry is nil
+func (retry *Retry) policy() *manager.RetryPolicy {
+	if retry == nil {
+		return nil
+	}
+	policy := &manager.RetryPolicy{MaxAttempts: retry.MaxAttempts}
+	switch retry.Backoff {
+	case ConstantBackoff:
+		policy.Backoff = manager.ConstantBackoff(retry.Delay)
+	case ExponentialBackoff:
+		policy.Backoff = manager.ExponentialBackoff(retry.Delay, retry.MaxDelay, retry.Multiplier)
+	}
+	if policy.Backoff != nil && retry.Jitter > 0 {
+		policy.Backoff = manager.JitteredBackoff(policy.Backoff, retry.Jitter)
+	}
+	return policy
+}
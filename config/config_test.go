@@ -0,0 +1,164 @@
+package config
+
+import (
+	"context"
+	"github.com/ericbrisrubio/go-workers-multipool/manager"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var resized int64
+
+func init() {
+	RegisterFunc("resize", func(interface{}) bool {
+		atomic.AddInt64(&resized, 1)
+		return true
+	})
+	RegisterFuncContext("upload", func(context.Context, interface{}) error { return nil })
+}
+
+var imagesConfig = &Config{Pools: []Pool{
+	{ID: "low-size", InitialWorkers: 3, MaxJobsInQueue: 10, Function: "resize", Start: true, Timeout: 30 * time.Second,
+		Retry: &Retry{MaxAttempts: 3, Backoff: ExponentialBackoff, Delay: time.Second, MaxDelay: time.Minute, Jitter: 0.2}},
+	{ID: "big-size", InitialWorkers: 2, MaxJobsInQueue: 10, Verbose: true, Backend: "channel", Function: "upload", Start: true,
+		DeadLetterQueue: "failed-images", RateLimit: &manager.RateLimit{Rate: 5, Burst: 2}},
+}}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		data   string
+	}{
+		{"YAML", YAML, `
+pools:
+  - id: low-size
+    initial_workers: 3
+    max_jobs_in_queue: 10
+    function: resize
+    timeout: 30s
+    retry:
+      max_attempts: 3
+      backoff: exponential
+      delay: 1s
+      max_delay: 1m
+      jitter: 0.2
+  - id: big-size
+    initial_workers: 2
+    max_jobs_in_queue: 10
+    verbose: true
+    backend: channel
+    function: upload
+    dead_letter_queue: failed-images
+    rate_limit: {rate: 5, burst: 2}
+`},
+		{"JSON", JSON, `{"pools": [
+  {"id": "low-size", "initial_workers": 3, "max_jobs_in_queue": 10, "function": "resize", "timeout": "30s",
+   "retry": {"max_attempts": 3, "backoff": "exponential", "delay": "1s", "max_delay": "1m", "jitter": 0.2}},
+  {"id": "big-size", "initial_workers": 2, "max_jobs_in_queue": 10, "verbose": true, "backend": "channel",
+   "function": "upload", "dead_letter_queue": "failed-images", "rate_limit": {"rate": 5, "burst": 2}}
+]}`},
+		{"TOML", TOML, `
+[[pools]]
+id = "low-size"
+initial_workers = 3
+max_jobs_in_queue = 10
+function = "resize"
+timeout = "30s"
+retry = {max_attempts = 3, backoff = "exponential", delay = "1s", max_delay = "1m", jitter = 0.2}
+
+[[pools]]
+id = "big-size"
+initial_workers = 2
+max_jobs_in_queue = 10
+verbose = true
+backend = "channel"
+function = "upload"
+dead_letter_queue = "failed-images"
+rate_limit = {rate = 5, burst = 2}
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.data), tt.format)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, imagesConfig) {
+				t.Errorf("Parse() = %+v, want %+v", got, imagesConfig)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"Missing pools", `{}`, "pools: is required"},
+		{"Pools not a list", `{"pools": {"id": "low-size"}}`, "pools: expected a list, got a mapping"},
+		{"Unknown root key", `{"pools": [], "pool": []}`, "pool: unknown key"},
+		{"Missing id", `{"pools": [{"max_jobs_in_queue": 10, "function": "resize"}]}`, "pools[0].id: is required"},
+		{"Duplicated id", `{"pools": [{"id": "low-size", "max_jobs_in_queue": 10, "function": "resize"}, {"id": "low-size", "max_jobs_in_queue": 10, "function": "resize"}]}`, "pools[1].id: duplicated pool id `low-size`"},
+		{"Integer expected", `{"pools": [{"id": "low-size", "initial_workers": "three", "max_jobs_in_queue": 10, "function": "resize"}]}`, `pools[0].initial_workers: expected an integer, got the string "three"`},
+		{"Fractional integer", `{"pools": [{"id": "low-size", "max_jobs_in_queue": 1.5, "function": "resize"}]}`, "pools[0].max_jobs_in_queue: expected an integer, got the number 1.5"},
+		{"Queue size out of bounds", `{"pools": [{"id": "low-size", "max_jobs_in_queue": 0, "function": "resize"}]}`, "pools[0].max_jobs_in_queue: has to be greater than 0"},
+		{"Function not registered", `{"pools": [{"id": "low-size", "max_jobs_in_queue": 10, "function": "crop"}]}`, "pools[0].function: function with `crop` name is not registered"},
+		{"Backend not registered", `{"pools": [{"id": "low-size", "max_jobs_in_queue": 10, "function": "resize", "backend": "threads"}]}`, "pools[0].backend: backend with `threads` name is not registered"},
+		{"Invalid duration", `{"pools": [{"id": "low-size", "max_jobs_in_queue": 10, "function": "resize", "timeout": 30}]}`, `pools[0].timeout: expected a duration such as "1m30s", got the number 30`},
+		{"Unknown pool key", `{"pools": [{"id": "low-size", "max_jobs_in_queue": 10, "function": "resize", "workers": 3}]}`, "pools[0].workers: unknown key"},
+		{"Unknown backoff", `{"pools": [{"id": "low-size", "max_jobs_in_queue": 10, "function": "resize", "retry": {"max_attempts": 3, "backoff": "linear"}}]}`, `pools[0].retry.backoff: expected constant or exponential, got "linear"`},
+		{"Delay without backoff", `{"pools": [{"id": "low-size", "max_jobs_in_queue": 10, "function": "resize", "retry": {"max_attempts": 3, "delay": "1s"}}]}`, "pools[0].retry.delay: requires a backoff"},
+		{"Max delay lower than delay", `{"pools": [{"id": "low-size", "max_jobs_in_queue": 10, "function": "resize", "retry": {"max_attempts": 3, "backoff": "exponential", "delay": "1m", "max_delay": "1s"}}]}`, "pools[0].retry.max_delay: has to be greater or equal to delay"},
+		{"Missing rate", `{"pools": [{"id": "low-size", "max_jobs_in_queue": 10, "function": "resize", "rate_limit": {"burst": 2}}]}`, "pools[0].rate_limit.rate: is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data), JSON)
+			if _, ok := err.(*KeyError); !ok || err.Error() != tt.want {
+				t.Errorf("Parse() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadManager(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pools.yaml")
+	os.WriteFile(path, []byte(`
+pools:
+  - id: low-size
+    initial_workers: 2
+    max_jobs_in_queue: 10
+    function: resize
+  - id: big-size
+    max_jobs_in_queue: 10
+    function: upload
+    start: false
+`), 0644)
+	poolsManager, err := LoadManager(path)
+	if err != nil {
+		t.Fatalf("LoadManager() error = %v", err)
+	}
+	if got := poolsManager.ListPools(); !reflect.DeepEqual(got, []string{"big-size", "low-size"}) {
+		t.Errorf("ListPools() = %v", got)
+	}
+	if stats, _ := poolsManager.PoolStats("big-size"); stats.State != manager.PoolDefined {
+		t.Errorf("state of a pool not to be started = %v", stats.State)
+	}
+	before := atomic.LoadInt64(&resized)
+	poolsManager.AddTaskToPool("low-size", "image.png")
+	poolsManager.StopPool("low-size")
+	if atomic.LoadInt64(&resized) != before+1 {
+		t.Errorf("the task was not processed by the registered function")
+	}
+
+	if _, err := LoadManager(filepath.Join(t.TempDir(), "pools.ini")); err == nil {
+		t.Errorf("LoadManager() of an unknown format must fail")
+	}
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+//KeyError is returned when the value of a key of a config file is missing or invalid.
+//Key is the path to the key, such as pools[2].retry.max_attempts
+type KeyError struct {
+	Key    string
+	Reason string
+}
+
+//Error returns the path to the key followed by the reason it is invalid
+func (err *KeyError) Error() string {
+	return fmt.Sprintf("%s: %s", err.Key, err.Reason)
+}
+
+//object is a mapping of a config file being decoded. It keeps the first error found and the keys read,
+//so the keys not part of the schema are reported
+type object struct {
+	key    string
+	values map[string]interface{}
+	read   map[string]bool
+	err    *error
+}
+
+//newObject returns the mapping value found at key, the failures of the mapping and its children are stored in err
+func newObject(key string, value interface{}, err *error) *object {
+	values, ok := value.(map[string]interface{})
+	if !ok {
+		fail(err, key, fmt.Sprintf("expected a mapping, got %s", describe(value)))
+		values = map[string]interface{}{}
+	}
+	return &object{key: key, values: values, read: make(map[string]bool), err: err}
+}
+
+//fail stores the error of key in err, unless there is one already
+func fail(err *error, key string, reason string) {
+	if *err == nil {
+		*err = &KeyError{Key: key, Reason: reason}
+	}
+}
+
+//path returns the path to the child name
+func (obj *object) path(name string) string {
+	if obj.key == "" {
+		return name
+	}
+	return obj.key + "." + name
+}
+
+func (obj *object) fail(name string, reason string) {
+	fail(obj.err, obj.path(name), reason)
+}
+
+//value returns the value of name and whether it is set
+func (obj *object) value(name string) (interface{}, bool) {
+	obj.read[name] = true
+	value, ok := obj.values[name]
+	return value, ok && value != nil
+}
+
+//has tells whether name is set
+func (obj *object) has(name string) bool {
+	value, ok := obj.values[name]
+	return ok && value != nil
+}
+
+//require reports name as missing if it is not set
+func (obj *object) require(name string) bool {
+	if !obj.has(name) {
+		obj.fail(name, "is required")
+		return false
+	}
+	return true
+}
+
+func (obj *object) string(name string) string {
+	value, ok := obj.value(name)
+	if !ok {
+		return ""
+	}
+	text, ok := value.(string)
+	if !ok {
+		obj.fail(name, fmt.Sprintf("expected a string, got %s", describe(value)))
+	}
+	return text
+}
+
+func (obj *object) bool(name string, fallback bool) bool {
+	value, ok := obj.value(name)
+	if !ok {
+		return fallback
+	}
+	flag, ok := value.(bool)
+	if !ok {
+		obj.fail(name, fmt.Sprintf("expected a boolean, got %s", describe(value)))
+	}
+	return flag
+}
+
+func (obj *object) float(name string) float64 {
+	value, ok := obj.value(name)
+	if !ok {
+		return 0
+	}
+	switch number := value.(type) {
+	case int:
+		return float64(number)
+	case int64:
+		return float64(number)
+	case uint64:
+		return float64(number)
+	case float64:
+		return number
+	case json.Number:
+		if float, err := number.Float64(); err == nil {
+			return float
+		}
+	}
+	obj.fail(name, fmt.Sprintf("expected a number, got %s", describe(value)))
+	return 0
+}
+
+func (obj *object) int(name string) int {
+	value, ok := obj.value(name)
+	if !ok {
+		return 0
+	}
+	switch number := value.(type) {
+	case int:
+		return number
+	case int64:
+		if number >= math.MinInt32 && number <= math.MaxInt32 {
+			return int(number)
+		}
+	case uint64:
+		if number <= math.MaxInt32 {
+			return int(number)
+		}
+	case float64:
+		if number == math.Trunc(number) && math.Abs(number) <= math.MaxInt32 {
+			return int(number)
+		}
+	case json.Number:
+		if integer, err := number.Int64(); err == nil && integer >= math.MinInt32 && integer <= math.MaxInt32 {
+			return int(integer)
+		}
+	}
+	obj.fail(name, fmt.Sprintf("expected an integer, got %s", describe(value)))
+	return 0
+}
+
+//duration decodes a duration written as a string such as "1m30s"
+func (obj *object) duration(name string) time.Duration {
+	value, ok := obj.value(name)
+	if !ok {
+		return 0
+	}
+	text, ok := value.(string)
+	if !ok {
+		obj.fail(name, fmt.Sprintf("expected a duration such as \"1m30s\", got %s", describe(value)))
+		return 0
+	}
+	duration, err := time.ParseDuration(text)
+	if err != nil {
+		obj.fail(name, fmt.Sprintf("expected a duration such as \"1m30s\", got %q", text))
+	}
+	return duration
+}
+
+//object returns the mapping of name, nil if it is not set
+func (obj *object) object(name string) *object {
+	value, ok := obj.value(name)
+	if !ok {
+		return nil
+	}
+	return newObject(obj.path(name), value, obj.err)
+}
+
+//list returns the mappings listed by name
+func (obj *object) list(name string) []*object {
+	value, ok := obj.value(name)
+	if !ok {
+		return nil
+	}
+	var items []interface{}
+	switch list := value.(type) {
+	case []interface{}:
+		items = list
+	case []map[string]interface{}:
+		for _, item := range list {
+			items = append(items, item)
+		}
+	default:
+		obj.fail(name, fmt.Sprintf("expected a list, got %s", describe(value)))
+		return nil
+	}
+	objects := make([]*object, len(items))
+	for i, item := range items {
+		objects[i] = newObject(fmt.Sprintf("%s[%d]", obj.path(name), i), item, obj.err)
+	}
+	return objects
+}
+
+//done reports the first key not read, in alphabetical order, as unknown
+func (obj *object) done() {
+	names := make([]string, 0, len(obj.values))
+	for name := range obj.values {
+		if !obj.read[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		obj.fail(names[0], "unknown key")
+	}
+}
+
+//describe returns the type of a decoded value for the error messages
+func describe(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "nothing"
+	case string:
+		return fmt.Sprintf("the string %q", value)
+	case bool:
+		return fmt.Sprintf("the boolean %t", value)
+	case int, int64, uint64, float64, json.Number:
+		return fmt.Sprintf("the number %v", value)
+	case map[string]interface{}:
+		return "a mapping"
+	case []interface{}, []map[string]interface{}:
+		return "a list"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"github.com/ericbrisrubio/go-workers-multipool/manager"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+//function is a worker function registered to be bound to the pools of config files, only one of them is set
+type function struct {
+	plain       func(interface{}) bool
+	withContext func(context.Context, interface{}) error
+}
+
+var functions = struct {
+	mutex     sync.RWMutex
+	functions map[string]function
+}{functions: make(map[string]function)}
+
+//RegisterFunc makes workerFunc available to the pools of config files under name, see Manager.SetFunc
+func RegisterFunc(name string, workerFunc func(interface{}) bool) error {
+	if workerFunc == nil {
+		return errors.New("worker function cannot be nil")
+	}
+	return register(name, function{plain: workerFunc})
+}
+
+//RegisterFuncContext makes workerFunc available to the pools of config files under name, see Manager.SetFuncContext
+func RegisterFuncContext(name string, workerFunc func(context.Context, interface{}) error) error {
+	if workerFunc == nil {
+		return errors.New("worker function cannot be nil")
+	}
+	return register(name, function{withContext: workerFunc})
+}
+
+func register(name string, fn function) error {
+	if name == "" {
+		return errors.New("function name cannot be empty")
+	}
+	functions.mutex.Lock()
+	defer functions.mutex.Unlock()
+	if _, ok := functions.functions[name]; ok {
+		return errors.New(fmt.Sprintf("a function with `%s` name already exist", name))
+	}
+	functions.functions[name] = fn
+	return nil
+}
+
+//lookupFunc returns the function registered under name
+func lookupFunc(name string) (function, error) {
+	functions.mutex.RLock()
+	defer functions.mutex.RUnlock()
+	fn, ok := functions.functions[name]
+	if !ok {
+		return function{}, errors.New(fmt.Sprintf("function with `%s` name is not registered", name))
+	}
+	return fn, nil
+}
+
+//bind sets to the pool of definition the worker function registered under its name
+func bind(poolsManager *manager.Manager, definition Pool) error {
+	fn, err := lookupFunc(definition.Function)
+	if err != nil {
+		return err
+	}
+	if fn.withContext != nil {
+		return poolsManager.SetFuncContext(definition.ID, fn.withContext)
+	}
+	return poolsManager.SetFunc(definition.ID, fn.plain)
+}
+
+//Build creates a manager with the pools of config bound to their worker functions, starting the ones to be started.
+//If a pool cannot be added the pools already added are shut down and the error is returned
+func (config *Config) Build() (*manager.Manager, error) {
+	poolsManager := &manager.Manager{}
+	for i, definition := range config.Pools {
+		if err := add(poolsManager, definition); err != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			poolsManager.Shutdown(ctx)
+			return nil, errors.Wrap(err, fmt.Sprintf("pools[%d] `%s`", i, definition.ID))
+		}
+	}
+	return poolsManager, nil
+}
+
+//add adds definition to poolsManager and starts it if it has to be started, the pool is removed again if it cannot be
+func add(poolsManager *manager.Manager, definition Pool) error {
+	if err := poolsManager.AddPoolWithOptions(definition.ID, definition.Options()); err != nil {
+		return err
+	}
+	err := bind(poolsManager, definition)
+	if err == nil && definition.Start {
+		err = poolsManager.StartPool(definition.ID)
+	}
+	if err != nil {
+		poolsManager.RemovePool(definition.ID)
+	}
+	return err
+}
+
+//LoadManager reads the config file at path and builds its manager, see Load and Build
+func LoadManager(path string) (*manager.Manager, error) {
+	config, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.Build()
+}
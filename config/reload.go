@@ -0,0 +1,344 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/ericbrisrubio/go-workers-multipool/manager"
+	"github.com/pkg/errors"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+)
+
+//errNotLive is the reason of the changes that require adding the pool again
+var errNotLive = errors.New("cannot be changed on a live pool, the pool has to be removed and added again")
+
+//DefaultStopTimeout is how long a reload waits by default for the queued tasks of a removed pool
+const DefaultStopTimeout = 30 * time.Second
+
+//Change is a difference between the config of a pool and the reloaded one
+type Change struct {
+	PoolID string
+	//Key is the key of the pool that changed, empty if the pool was added or removed
+	Key string
+	//Err tells why the change could not be applied, nil if it was
+	Err error
+}
+
+//ReloadReport describes what a reload changed in the manager
+type ReloadReport struct {
+	At time.Time
+	//Added and Removed are the ids of the pools added and removed
+	Added   []string
+	Removed []string
+	//Applied are the keys of the pools changed live
+	Applied []Change
+	//Rejected are the changes that could not be applied, they are tried again by the next reload
+	Rejected []Change
+	//Err is set if the config file could not be loaded, nothing is applied then
+	Err error
+}
+
+//Reloader keeps the manager built from a config file in line with it: the pools added to the file are added,
+//the pools removed from it are stopped and removed and the keys changed are applied to the running pools
+type Reloader struct {
+	mutex   sync.Mutex
+	path    string
+	manager *manager.Manager
+	//config is the config applied to the manager, data the content of the file it was loaded from
+	config *Config
+	data   []byte
+	//failed is the content of the file that could not be loaded last, so Watch does not reload it again until it changes
+	failed []byte
+	//stopTimeout bounds the wait for the queued tasks of a removed pool
+	stopTimeout time.Duration
+}
+
+//NewReloader loads the config file at path and builds its manager, see LoadManager
+func NewReloader(path string) (*Reloader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := parseFile(path, data)
+	if err != nil {
+		return nil, err
+	}
+	poolsManager, err := config.Build()
+	if err != nil {
+		return nil, err
+	}
+	return &Reloader{path: path, manager: poolsManager, config: config, data: data, stopTimeout: DefaultStopTimeout}, nil
+}
+
+//SetStopTimeout sets how long a reload waits for the queued tasks of a removed pool. Once it is over the pool
+//is left running, the removal is reported as rejected and tried again by the next reload
+func (reloader *Reloader) SetStopTimeout(timeout time.Duration) error {
+	if timeout <= 0 {
+		return errors.New(fmt.Sprintf("stop timeout must be greater than 0, got %v", timeout))
+	}
+	reloader.mutex.Lock()
+	defer reloader.mutex.Unlock()
+	reloader.stopTimeout = timeout
+	return nil
+}
+
+//Manager returns the manager kept in line with the config file
+func (reloader *Reloader) Manager() *manager.Manager {
+	return reloader.manager
+}
+
+//Reload loads the config file again and applies to the manager its differences with the config applied before.
+//If the file cannot be loaded its error is returned and nothing is applied
+func (reloader *Reloader) Reload() (ReloadReport, error) {
+	reloader.mutex.Lock()
+	defer reloader.mutex.Unlock()
+	report := ReloadReport{At: time.Now()}
+	data, err := os.ReadFile(reloader.path)
+	if err != nil {
+		report.Err = err
+		return report, err
+	}
+	config, err := parseFile(reloader.path, data)
+	if err != nil {
+		reloader.failed = data
+		report.Err = err
+		return report, err
+	}
+	reloader.config = reloader.apply(config, &report)
+	reloader.data = data
+	reloader.failed = nil
+	return report, nil
+}
+
+//modified tells whether the content of the config file changed since it was loaded or since it last failed to load
+func (reloader *Reloader) modified() bool {
+	data, err := os.ReadFile(reloader.path)
+	if err != nil {
+		return false
+	}
+	reloader.mutex.Lock()
+	defer reloader.mutex.Unlock()
+	return !bytes.Equal(data, reloader.data) && !bytes.Equal(data, reloader.failed)
+}
+
+//Watch reloads the config file whenever the process receives SIGHUP or, if interval is greater than 0,
+//its content is found modified by a check every interval. As editors may write the file in several steps, a modified
+//file is reloaded once its size and modification time did not change for an interval. A content that cannot be loaded
+//is reported once, until it is modified again. It returns once ctx is done.
+//The report of each reload is sent to reports unless it is nil, the reloads wait until it is taken
+func (reloader *Reloader) Watch(ctx context.Context, interval time.Duration, reports chan<- ReloadReport) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+	var ticks <-chan time.Time
+	var last os.FileInfo
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+		case <-ticks:
+			info, err := os.Stat(reloader.path)
+			if err != nil {
+				continue
+			}
+			settled := last != nil && info.Size() == last.Size() && info.ModTime().Equal(last.ModTime())
+			last = info
+			if !settled || !reloader.modified() {
+				continue
+			}
+		}
+		report, _ := reloader.Reload()
+		if reports != nil {
+			select {
+			case reports <- report:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+//apply changes the manager from the applied config to config and returns the config applied in the end,
+//which keeps the previous definitions of what was rejected
+func (reloader *Reloader) apply(config *Config, report *ReloadReport) *Config {
+	next := make(map[string]bool, len(config.Pools))
+	for _, definition := range config.Pools {
+		next[definition.ID] = true
+	}
+	previous := make(map[string]Pool, len(reloader.config.Pools))
+	applied := &Config{}
+	for _, definition := range reloader.config.Pools {
+		previous[definition.ID] = definition
+		if next[definition.ID] {
+			continue
+		}
+		if err := reloader.remove(definition.ID); err != nil {
+			report.Rejected = append(report.Rejected, Change{PoolID: definition.ID, Err: err})
+			applied.Pools = append(applied.Pools, definition)
+			continue
+		}
+		report.Removed = append(report.Removed, definition.ID)
+	}
+	for _, definition := range config.Pools {
+		current, ok := previous[definition.ID]
+		if !ok {
+			if err := add(reloader.manager, definition); err != nil {
+				report.Rejected = append(report.Rejected, Change{PoolID: definition.ID, Err: err})
+				continue
+			}
+			report.Added = append(report.Added, definition.ID)
+			applied.Pools = append(applied.Pools, definition)
+			continue
+		}
+		effective := definition
+		for _, setting := range settings {
+			if !setting.changed(current, definition) {
+				continue
+			}
+			change := Change{PoolID: definition.ID, Key: setting.key}
+			if change.Err = setting.apply(reloader.manager, definition); change.Err != nil {
+				setting.keep(&effective, current)
+				report.Rejected = append(report.Rejected, change)
+			} else {
+				report.Applied = append(report.Applied, change)
+			}
+		}
+		applied.Pools = append(applied.Pools, effective)
+	}
+	return applied
+}
+
+//remove stops poolID, waiting up to the stop timeout for its queued tasks, and removes it. A pool already removed is left as is.
+//It has to be called holding the mutex
+func (reloader *Reloader) remove(poolID string) error {
+	if _, err := reloader.manager.PoolStats(poolID); err != nil {
+		return nil
+	}
+	if running(reloader.manager, poolID) {
+		ctx, cancel := context.WithTimeout(context.Background(), reloader.stopTimeout)
+		defer cancel()
+		// once the timeout is over the pool is put back as it was, so the next reload can remove it
+		if err := reloader.manager.StopPoolContext(ctx, poolID); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("stopping pool %s", poolID))
+		}
+	}
+	return reloader.manager.RemovePool(poolID)
+}
+
+//running tells whether poolID is started or paused
+func running(poolsManager *manager.Manager, poolID string) bool {
+	stats, err := poolsManager.PoolStats(poolID)
+	return err == nil && (stats.State == manager.PoolStarted || stats.State == manager.PoolPaused)
+}
+
+//setting is a key of a pool a reload can change
+type setting struct {
+	key     string
+	changed func(previous, next Pool) bool
+	//apply applies the value of next to the pool
+	apply func(poolsManager *manager.Manager, next Pool) error
+	//keep sets to effective the value of previous once apply failed
+	keep func(effective *Pool, previous Pool)
+}
+
+var settings = []setting{
+	{
+		key:     "backend",
+		changed: func(previous, next Pool) bool { return previous.Backend != next.Backend },
+		apply:   func(*manager.Manager, Pool) error { return errNotLive },
+		keep:    func(effective *Pool, previous Pool) { effective.Backend = previous.Backend },
+	},
+	{
+		key:     "verbose",
+		changed: func(previous, next Pool) bool { return previous.Verbose != next.Verbose },
+		apply:   func(*manager.Manager, Pool) error { return errNotLive },
+		keep:    func(effective *Pool, previous Pool) { effective.Verbose = previous.Verbose },
+	},
+	{
+		key:     "max_jobs_in_queue",
+		changed: func(previous, next Pool) bool { return previous.MaxJobsInQueue != next.MaxJobsInQueue },
+		apply: func(poolsManager *manager.Manager, next Pool) error {
+			return poolsManager.SetMaxJobsInQueue(next.ID, next.MaxJobsInQueue)
+		},
+		keep: func(effective *Pool, previous Pool) { effective.MaxJobsInQueue = previous.MaxJobsInQueue },
+	},
+	{
+		key:     "initial_workers",
+		changed: func(previous, next Pool) bool { return previous.InitialWorkers != next.InitialWorkers },
+		apply: func(poolsManager *manager.Manager, next Pool) error {
+			if running(poolsManager, next.ID) {
+				if err := poolsManager.EditPoolWorkersAmount(next.ID, next.InitialWorkers); err != nil {
+					return err
+				}
+			}
+			return poolsManager.SetInitialWorkers(next.ID, next.InitialWorkers)
+		},
+		keep: func(effective *Pool, previous Pool) { effective.InitialWorkers = previous.InitialWorkers },
+	},
+	{
+		key:     "function",
+		changed: func(previous, next Pool) bool { return previous.Function != next.Function },
+		apply:   bind,
+		keep:    func(effective *Pool, previous Pool) { effective.Function = previous.Function },
+	},
+	{
+		key:     "timeout",
+		changed: func(previous, next Pool) bool { return previous.Timeout != next.Timeout },
+		apply: func(poolsManager *manager.Manager, next Pool) error {
+			return poolsManager.SetTimeout(next.ID, next.Timeout)
+		},
+		keep: func(effective *Pool, previous Pool) { effective.Timeout = previous.Timeout },
+	},
+	{
+		key:     "retry",
+		changed: func(previous, next Pool) bool { return !reflect.DeepEqual(previous.Retry, next.Retry) },
+		apply: func(poolsManager *manager.Manager, next Pool) error {
+			return poolsManager.SetRetryPolicy(next.ID, next.Retry.policy())
+		},
+		keep: func(effective *Pool, previous Pool) { effective.Retry = previous.Retry },
+	},
+	{
+		key:     "rate_limit",
+		changed: func(previous, next Pool) bool { return !reflect.DeepEqual(previous.RateLimit, next.RateLimit) },
+		apply: func(poolsManager *manager.Manager, next Pool) error {
+			return poolsManager.SetRateLimit(next.ID, next.Options().RateLimit)
+		},
+		keep: func(effective *Pool, previous Pool) { effective.RateLimit = previous.RateLimit },
+	},
+	{
+		key:     "dead_letter_queue",
+		changed: func(previous, next Pool) bool { return previous.DeadLetterQueue != next.DeadLetterQueue },
+		apply: func(poolsManager *manager.Manager, next Pool) error {
+			return poolsManager.SetDeadLetterQueue(next.ID, next.DeadLetterQueue)
+		},
+		keep: func(effective *Pool, previous Pool) { effective.DeadLetterQueue = previous.DeadLetterQueue },
+	},
+	{
+		key:     "start",
+		changed: func(previous, next Pool) bool { return previous.Start != next.Start },
+		apply: func(poolsManager *manager.Manager, next Pool) error {
+			stats, err := poolsManager.PoolStats(next.ID)
+			switch {
+			case err != nil:
+				return err
+			case next.Start && stats.State == manager.PoolDefined:
+				return poolsManager.StartPool(next.ID)
+			case !next.Start && running(poolsManager, next.ID):
+				return errors.New("running pools are not stopped by a reload, use StopPool")
+			}
+			return nil
+		},
+		keep: func(effective *Pool, previous Pool) { effective.Start = previous.Start },
+	},
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"github.com/ericbrisrubio/go-workers-multipool/manager"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const imagesPools = `
+pools:
+  - id: low-size
+    initial_workers: 2
+    max_jobs_in_queue: 10
+    function: resize
+  - id: big-size
+    max_jobs_in_queue: 10
+    function: resize
+  - id: archive
+    max_jobs_in_queue: 5
+    function: resize
+    start: false
+`
+
+//release is closed to let the tasks of the pools running the hold function return
+var release chan struct{}
+
+func init() {
+	RegisterFunc("hold", func(interface{}) bool {
+		<-release
+		return true
+	})
+}
+
+//writeFile replaces the file at path with content at once, so a watcher never reads it half written
+func writeFile(t *testing.T, path string, content []byte) {
+	t.Helper()
+	temporary := path + ".tmp"
+	if err := os.WriteFile(temporary, content, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.Rename(temporary, path); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+}
+
+//changes returns the pool id and key of changes, the key is followed by ! if the change was rejected
+func changes(report ReloadReport) []string {
+	keys := []string{}
+	for _, change := range append(report.Applied, report.Rejected...) {
+		key := change.PoolID + "." + change.Key
+		if change.Err != nil {
+			key += "!"
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestReloader_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pools.yaml")
+	os.WriteFile(path, []byte(imagesPools), 0644)
+	reloader, err := NewReloader(path)
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+	poolsManager := reloader.Manager()
+	os.WriteFile(path, []byte(`
+pools:
+  - id: low-size
+    initial_workers: 3
+    max_jobs_in_queue: 20
+    function: resize
+    timeout: 5s
+    retry: {max_attempts: 3}
+  - id: archive
+    max_jobs_in_queue: 5
+    backend: channel
+    function: resize
+  - id: thumbnails
+    max_jobs_in_queue: 10
+    function: resize
+`), 0644)
+	report, err := reloader.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if !reflect.DeepEqual(report.Added, []string{"thumbnails"}) || !reflect.DeepEqual(report.Removed, []string{"big-size"}) {
+		t.Errorf("Reload() added %v and removed %v", report.Added, report.Removed)
+	}
+	want := []string{"low-size.initial_workers", "low-size.timeout", "low-size.retry", "archive.start", "low-size.max_jobs_in_queue!", "archive.backend!"}
+	if got := changes(report); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reload() changes = %v, want %v", got, want)
+	}
+	if got := poolsManager.ListPools(); !reflect.DeepEqual(got, []string{"archive", "low-size", "thumbnails"}) {
+		t.Errorf("ListPools() once reloaded = %v", got)
+	}
+	if stats, _ := poolsManager.PoolStats("archive"); stats.State != manager.PoolStarted {
+		t.Errorf("state of archive once reloaded = %v, want started", stats.State)
+	}
+	deadline := time.Now().Add(time.Second)
+	for stats, _ := poolsManager.PoolStats("low-size"); stats.TotalWorkers != 3; stats, _ = poolsManager.PoolStats("low-size") {
+		if time.Now().After(deadline) {
+			t.Fatalf("TotalWorkers of low-size = %d once reloaded, want 3", stats.TotalWorkers)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// the rejected changes are still pending, the applied ones are not applied again
+	report, _ = reloader.Reload()
+	if got := changes(report); !reflect.DeepEqual(got, []string{"low-size.max_jobs_in_queue!", "archive.backend!"}) {
+		t.Errorf("second Reload() changes = %v", got)
+	}
+
+	os.WriteFile(path, []byte(`pools: [{id: low-size, max_jobs_in_queue: ten}]`), 0644)
+	if report, err := reloader.Reload(); err == nil || report.Err != err {
+		t.Errorf("Reload() of an invalid config error = %v, report error = %v", err, report.Err)
+	}
+	if got := poolsManager.ListPools(); len(got) != 3 {
+		t.Errorf("ListPools() once an invalid config was reloaded = %v", got)
+	}
+}
+
+func TestReloader_RemoveTimeout(t *testing.T) {
+	release = make(chan struct{})
+	path := filepath.Join(t.TempDir(), "pools.yaml")
+	os.WriteFile(path, []byte(imagesPools+`  - id: uploads
+    initial_workers: 1
+    max_jobs_in_queue: 5
+    function: hold
+`), 0644)
+	reloader, err := NewReloader(path)
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+	if err := reloader.SetStopTimeout(0); err == nil {
+		t.Error("SetStopTimeout() must fail for a timeout not greater than 0")
+	}
+	reloader.SetStopTimeout(20 * time.Millisecond)
+	poolsManager := reloader.Manager()
+	poolsManager.AddTaskToPool("uploads", "first")
+	poolsManager.AddTaskToPool("uploads", "second")
+
+	os.WriteFile(path, []byte(imagesPools), 0644)
+	report, err := reloader.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if len(report.Removed) != 0 || len(report.Rejected) != 1 || !errors.Is(report.Rejected[0].Err, context.DeadlineExceeded) {
+		t.Errorf("Reload() of a pool not drained in time removed %v and rejected %v", report.Removed, report.Rejected)
+	}
+	if stats, _ := poolsManager.PoolStats("uploads"); stats.State != manager.PoolStarted {
+		t.Errorf("state of a pool not drained in time = %v, want started", stats.State)
+	}
+
+	// the removal is tried again by the next reload
+	close(release)
+	report, _ = reloader.Reload()
+	if !reflect.DeepEqual(report.Removed, []string{"uploads"}) || len(report.Rejected) != 0 {
+		t.Errorf("second Reload() removed %v and rejected %v", report.Removed, report.Rejected)
+	}
+}
+
+func TestReloader_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pools.yaml")
+	writeFile(t, path, []byte(imagesPools))
+	reloader, err := NewReloader(path)
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reports := make(chan ReloadReport)
+	go reloader.Watch(ctx, 10*time.Millisecond, reports)
+
+	modified := imagesPools + `  - id: thumbnails
+    max_jobs_in_queue: 10
+    function: resize
+`
+	writeFile(t, path, []byte(modified))
+	select {
+	case report := <-reports:
+		if !reflect.DeepEqual(report.Added, []string{"thumbnails"}) {
+			t.Errorf("report of the modified file added %v", report.Added)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("the modified file was not reloaded")
+	}
+
+	writeFile(t, path, []byte(`pools: [{id: low-size, max_jobs_in_queue: ten}]`))
+	select {
+	case report := <-reports:
+		if report.Err == nil {
+			t.Errorf("report of the invalid file has no error")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("the invalid file was not reloaded")
+	}
+	select {
+	case report := <-reports:
+		t.Errorf("the invalid file was reloaded again without being modified, report = %+v", report)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// the content loaded last is back, there is nothing to reload
+	writeFile(t, path, []byte(modified))
+
+	// the signal is sent until the watcher is listening, the process must not be terminated meanwhile
+	ignored := make(chan os.Signal, 1)
+	signal.Notify(ignored, syscall.SIGHUP)
+	defer signal.Stop(ignored)
+	process, _ := os.FindProcess(os.Getpid())
+	deadline := time.After(time.Second)
+	for {
+		process.Signal(syscall.SIGHUP)
+		select {
+		case report := <-reports:
+			if report.Err != nil || len(report.Added)+len(report.Removed)+len(report.Applied)+len(report.Rejected) != 0 {
+				t.Errorf("report of SIGHUP without changes = %+v", report)
+			}
+			return
+		case <-deadline:
+			t.Fatalf("SIGHUP did not reload the file")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
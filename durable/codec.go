@@ -0,0 +1,76 @@
+package durable
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+//Codec turns the data of the tasks into the payloads stored in a Log and back
+type Codec interface {
+	Encode(data interface{}) ([]byte, error)
+	Decode(payload []byte) (interface{}, error)
+}
+
+//JSONCodec stores the data of the tasks as JSON, decoding it as a T
+type JSONCodec[T any] struct{}
+
+//Encode returns data marshalled as JSON
+func (JSONCodec[T]) Encode(data interface{}) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+//Decode unmarshals payload into a T
+func (JSONCodec[T]) Decode(payload []byte) (interface{}, error) {
+	var data T
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+//GobCodec stores the data of the tasks, which have to be of type T, encoded with encoding/gob
+type GobCodec[T any] struct{}
+
+//Encode returns data gob encoded, it fails if data is not a T
+func (GobCodec[T]) Encode(data interface{}) ([]byte, error) {
+	typed, ok := data.(T)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("gob codec cannot encode data of type %T", data))
+	}
+	buffer := new(bytes.Buffer)
+	if err := gob.NewEncoder(buffer).Encode(&typed); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+//Decode gob decodes payload into a T
+func (GobCodec[T]) Decode(payload []byte) (interface{}, error) {
+	var data T
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+//BytesCodec stores the data of the tasks as is, it has to be a []byte or a string and is decoded as a []byte
+type BytesCodec struct{}
+
+//Encode returns the bytes of data, it fails if data is neither a []byte nor a string
+func (BytesCodec) Encode(data interface{}) ([]byte, error) {
+	switch raw := data.(type) {
+	case []byte:
+		return raw, nil
+	case string:
+		return []byte(raw), nil
+	}
+	return nil, errors.New(fmt.Sprintf("bytes codec cannot encode data of type %T", data))
+}
+
+//Decode returns payload as is
+func (BytesCodec) Decode(payload []byte) (interface{}, error) {
+	return payload, nil
+}
@@ -0,0 +1,43 @@
+package durable
+
+import (
+	"reflect"
+	"testing"
+)
+
+type order struct {
+	Customer string
+	Amount   int
+}
+
+func TestCodecs(t *testing.T) {
+	tests := []struct {
+		name    string
+		codec   Codec
+		data    interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{"JSON round trip", JSONCodec[order]{}, order{"customer-1", 10}, order{"customer-1", 10}, false},
+		{"Gob round trip", GobCodec[order]{}, order{"customer-1", 10}, order{"customer-1", 10}, false},
+		{"Gob returns error for data of another type", GobCodec[order]{}, "order", nil, true},
+		{"Bytes round trip", BytesCodec{}, []byte("order"), []byte("order"), false},
+		{"Bytes encodes strings", BytesCodec{}, "order", []byte("order"), false},
+		{"Bytes returns error for data of another type", BytesCodec{}, 10, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := tt.codec.Encode(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Encode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, err := tt.codec.Decode(payload)
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package durable
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"github.com/pkg/errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+//ErrClosed is returned when a closed log is written
+var ErrClosed = errors.New("durable log is closed")
+
+const (
+	opAppend byte = 1
+	opAck    byte = 2
+	//opLast records the highest id handed out, so the ids keep increasing once the acked entries are compacted away
+	opLast byte = 3
+	//headerSize is the size of the op, the id and the payload length heading every record
+	headerSize = 1 + 8 + 4
+	//MaxPayloadSize is the size limit of a payload
+	MaxPayloadSize = 64 << 20
+	//compactThreshold is the amount of acked records from which the log is compacted, once they outnumber the pending ones
+	compactThreshold = 1024
+)
+
+//Entry is a payload appended to a log and not acked yet
+type Entry struct {
+	ID      uint64
+	Payload []byte
+}
+
+//Log is an append-only file of payloads waiting to be acked. Every append and ack is synced to disk before
+//returning, so the entries not acked survive a crash or a restart and are given back by Pending once reopened.
+//Each record is checksummed: a record torn by a crash is dropped when the log is opened
+type Log struct {
+	mutex   sync.Mutex
+	path    string
+	file    *os.File
+	pending map[uint64][]byte
+	nextID  uint64
+	//acked is the amount of records in the file about entries already acked
+	acked  int
+	closed bool
+}
+
+//Open opens the log stored at path, creating it if needed, and loads the entries not acked yet
+func Open(path string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	log := &Log{path: path, file: file, pending: make(map[uint64][]byte), nextID: 1}
+	valid, err := log.load()
+	if err == nil {
+		// drops a record torn by a crash, the following appends would be unreadable otherwise
+		err = file.Truncate(valid)
+	}
+	if err == nil {
+		_, err = file.Seek(valid, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, fmt.Sprintf("opening durable log %s", path))
+	}
+	return log, nil
+}
+
+//load replays the records of the file and returns the offset where the valid ones end
+func (log *Log) load() (int64, error) {
+	reader := bufio.NewReader(log.file)
+	var offset int64
+	header := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return offset, nil
+		}
+		op, id, length := header[0], binary.BigEndian.Uint64(header[1:9]), binary.BigEndian.Uint32(header[9:13])
+		if (op != opAppend && op != opAck && op != opLast) || length > MaxPayloadSize {
+			return offset, nil
+		}
+		body := make([]byte, int(length)+4)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return offset, nil
+		}
+		payload := body[:length]
+		if checksum(header, payload) != binary.BigEndian.Uint32(body[length:]) {
+			return offset, nil
+		}
+		offset += int64(headerSize + len(body))
+		switch op {
+		case opAppend:
+			log.pending[id] = payload
+		case opAck:
+			delete(log.pending, id)
+			log.acked += 2
+		}
+		if id >= log.nextID {
+			log.nextID = id + 1
+		}
+	}
+}
+
+func checksum(header, payload []byte) uint32 {
+	return crc32.Update(crc32.ChecksumIEEE(header), crc32.IEEETable, payload)
+}
+
+//encode returns the record of op for the entry id
+func encode(op byte, id uint64, payload []byte) []byte {
+	record := make([]byte, headerSize+len(payload)+4)
+	record[0] = op
+	binary.BigEndian.PutUint64(record[1:9], id)
+	binary.BigEndian.PutUint32(record[9:13], uint32(len(payload)))
+	copy(record[headerSize:], payload)
+	binary.BigEndian.PutUint32(record[headerSize+len(payload):], checksum(record[:headerSize], payload))
+	return record
+}
+
+//write appends record to the file and syncs it, it has to be called holding the mutex
+func (log *Log) write(record []byte) error {
+	if log.closed {
+		return ErrClosed
+	}
+	if _, err := log.file.Write(record); err != nil {
+		return err
+	}
+	return log.file.Sync()
+}
+
+//Append stores payload and returns the id to ack it
+func (log *Log) Append(payload []byte) (uint64, error) {
+	if len(payload) > MaxPayloadSize {
+		return 0, errors.New(fmt.Sprintf("payload of %d bytes exceeds the size limit of %d bytes", len(payload), MaxPayloadSize))
+	}
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	id := log.nextID
+	if err := log.write(encode(opAppend, id, payload)); err != nil {
+		return 0, err
+	}
+	log.nextID++
+	log.pending[id] = payload
+	return id, nil
+}
+
+//Ack removes the entry id, so it is not given back anymore. Acking an unknown entry does nothing
+func (log *Log) Ack(id uint64) error {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	if _, ok := log.pending[id]; !ok {
+		return nil
+	}
+	if err := log.write(encode(opAck, id, nil)); err != nil {
+		return err
+	}
+	delete(log.pending, id)
+	// the record of the append and the one of the ack are both garbage from now on
+	log.acked += 2
+	if log.acked >= compactThreshold && log.acked > len(log.pending) {
+		return log.compact()
+	}
+	return nil
+}
+
+//Pending returns the entries not acked, in the order they were appended
+func (log *Log) Pending() []Entry {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	return log.entries()
+}
+
+//entries returns the entries not acked in order, it has to be called holding the mutex
+func (log *Log) entries() []Entry {
+	entries := make([]Entry, 0, len(log.pending))
+	for id, payload := range log.pending {
+		entries = append(entries, Entry{ID: id, Payload: payload})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+//Len returns the amount of entries not acked
+func (log *Log) Len() int {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	return len(log.pending)
+}
+
+//Compact rewrites the file keeping only the entries not acked
+func (log *Log) Compact() error {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	if log.closed {
+		return ErrClosed
+	}
+	return log.compact()
+}
+
+//compact implements Compact writing a new file that replaces the current one, it has to be called holding the mutex
+func (log *Log) compact() error {
+	temporary := log.path + ".compact"
+	file, err := os.OpenFile(temporary, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	// an ack of an entry compacted away must not settle a new entry reusing its id
+	_, err = writer.Write(encode(opLast, log.nextID-1, nil))
+	for _, entry := range log.entries() {
+		if err != nil {
+			break
+		}
+		_, err = writer.Write(encode(opAppend, entry.ID, entry.Payload))
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = os.Rename(temporary, log.path)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(temporary)
+		return err
+	}
+	log.file.Close()
+	log.file = file
+	log.acked = 0
+	// the rename survives a crash once the directory holding the log is synced
+	return syncDir(filepath.Dir(log.path))
+}
+
+//syncDir syncs the directory at path to disk
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+//Close closes the file of the log, the entries not acked are given back once it is opened again
+func (log *Log) Close() error {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	if log.closed {
+		return nil
+	}
+	log.closed = true
+	return log.file.Close()
+}
@@ -0,0 +1,115 @@
+package durable
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLog_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.log")
+	log, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	first, _ := log.Append([]byte("first"))
+	second, _ := log.Append([]byte("second"))
+	log.Append([]byte("third"))
+	if err := log.Ack(second); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	if err := log.Ack(second); err != nil {
+		t.Errorf("Ack() of an entry already acked error = %v", err)
+	}
+	log.Close()
+	if _, err := log.Append([]byte("fourth")); err != ErrClosed {
+		t.Errorf("Append() on a closed log error = %v, want ErrClosed", err)
+	}
+
+	log, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer log.Close()
+	want := []Entry{{ID: first, Payload: []byte("first")}, {ID: 3, Payload: []byte("third")}}
+	if got := log.Pending(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Pending() once reopened = %v, want %v", got, want)
+	}
+	if id, _ := log.Append([]byte("fourth")); id != 4 {
+		t.Errorf("Append() once reopened id = %d, want 4", id)
+	}
+}
+
+func TestLog_TornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.log")
+	log, _ := Open(path)
+	log.Append([]byte("complete"))
+	log.Append([]byte("torn by a crash"))
+	log.Close()
+	info, _ := os.Stat(path)
+	os.Truncate(path, info.Size()-3)
+
+	log, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if entries := log.Pending(); len(entries) != 1 || string(entries[0].Payload) != "complete" {
+		t.Errorf("Pending() with a torn record = %v", entries)
+	}
+	log.Append([]byte("after the crash"))
+	log.Close()
+	log, _ = Open(path)
+	defer log.Close()
+	if entries := log.Pending(); len(entries) != 2 || string(entries[1].Payload) != "after the crash" {
+		t.Errorf("Pending() of the records appended after the torn one = %v", entries)
+	}
+}
+
+func TestLog_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.log")
+	log, _ := Open(path)
+	for i := 0; i < compactThreshold; i++ {
+		id, _ := log.Append([]byte("processed"))
+		log.Ack(id)
+	}
+	kept, _ := log.Append([]byte("kept"))
+	if log.acked >= compactThreshold {
+		t.Errorf("the log must be compacted once %d records are acked", log.acked)
+	}
+	log.Close()
+	info, _ := os.Stat(path)
+	if info.Size() > int64(compactThreshold*(headerSize+len("processed")+4)) {
+		t.Errorf("size of the compacted log = %d", info.Size())
+	}
+	log, _ = Open(path)
+	defer log.Close()
+	if entries := log.Pending(); len(entries) != 1 || entries[0].ID != kept {
+		t.Errorf("Pending() once compacted = %v", entries)
+	}
+	if err := log.Compact(); err != nil || log.Len() != 1 {
+		t.Errorf("Compact() error = %v, Len() = %d", err, log.Len())
+	}
+}
+
+func TestLog_IDsAfterCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.log")
+	log, _ := Open(path)
+	first, _ := log.Append([]byte("first"))
+	log.Ack(first)
+	if err := log.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	log.Close()
+	log, _ = Open(path)
+	defer log.Close()
+	second, _ := log.Append([]byte("second"))
+	if second <= first {
+		t.Fatalf("Append() once every entry was compacted away returned id %d, not above %d", second, first)
+	}
+	// a late ack of the compacted entry must not settle the new one
+	log.Ack(first)
+	if entries := log.Pending(); len(entries) != 1 || entries[0].ID != second {
+		t.Errorf("Pending() after a stale ack = %v", entries)
+	}
+}
@@ -1,15 +1,17 @@
 module github.com/ericbrisrubio/go-workers-multipool
 
-go 1.13
+go 1.18
 
 require (
 	bou.ke/monkey v1.0.2
-	github.com/enriquebris/goconcurrentcounter v0.0.0-20200419230532-4756c242775c // indirect
-	github.com/enriquebris/goconcurrentqueue v0.6.0 // indirect
+	github.com/BurntSushi/toml v1.3.2
 	github.com/enriquebris/goworkerpool v0.10.0
 	github.com/pkg/errors v0.9.1
-	github.com/spf13/afero v1.2.2 // indirect
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/enriquebris/goconcurrentcounter v0.0.0-20200419230532-4756c242775c // indirect
+	github.com/enriquebris/goconcurrentqueue v0.6.0 // indirect
 	github.com/stretchr/testify v1.5.1 // indirect
-	golang.org/x/lint v0.0.0-20200302205851-738671d3881b // indirect
-	golang.org/x/tools v0.0.0-20200504193531-9bfbc385433f // indirect
 )
@@ -0,0 +1,295 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/ericbrisrubio/go-workers-multipool/pool"
+	"github.com/pkg/errors"
+	"math"
+	"sync"
+	"time"
+)
+
+//AutoscalePolicy makes the manager resize the workers of a started pool following its load
+type AutoscalePolicy struct {
+	//MinWorkers and MaxWorkers bound the amount of workers of the pool
+	MinWorkers int
+	MaxWorkers int
+	//TargetQueueDepth is the amount of queued tasks per worker aimed at, 0 means the queue depth is not taken into account
+	TargetQueueDepth int
+	//TargetWait is how long a new task is aimed to wait before a worker picks it up, as estimated from the average
+	//run time of the tasks of the pool. 0 means the wait is not taken into account.
+	//If both targets are set the pool gets the workers needed to meet both of them
+	TargetWait time.Duration
+	//Hysteresis is the fraction the load can deviate from the target before the pool is resized, e.g. 0.2 resizes
+	//the pool once the load is 20% above or below the target
+	Hysteresis float64
+	//ScaleUpCooldown and ScaleDownCooldown are the minimum time between a scaling decision and the previous one,
+	//they give the workers time to settle and the load to reflect the new amount
+	ScaleUpCooldown   time.Duration
+	ScaleDownCooldown time.Duration
+	//Interval is how often the load of the pool is evaluated, 0 means every second
+	Interval time.Duration
+}
+
+func (policy *AutoscalePolicy) validate() error {
+	if policy.MinWorkers < 0 {
+		return errors.New("autoscale MinWorkers has to be greater or equal to 0")
+	}
+	if policy.MaxWorkers < 1 || policy.MaxWorkers < policy.MinWorkers {
+		return errors.New("autoscale MaxWorkers has to be greater than 0 and greater or equal to MinWorkers")
+	}
+	if policy.TargetQueueDepth < 0 || policy.TargetWait < 0 {
+		return errors.New("autoscale targets have to be greater or equal to 0")
+	}
+	if policy.TargetQueueDepth == 0 && policy.TargetWait == 0 {
+		return errors.New("autoscale needs a TargetQueueDepth or a TargetWait")
+	}
+	if policy.Hysteresis < 0 || policy.Hysteresis >= 1 {
+		return errors.New("autoscale Hysteresis has to be between 0 and 1")
+	}
+	if policy.ScaleUpCooldown < 0 || policy.ScaleDownCooldown < 0 || policy.Interval < 0 {
+		return errors.New("autoscale cooldowns and Interval have to be greater or equal to 0")
+	}
+	return nil
+}
+
+//ScalingReason tells why the autoscaler resized a pool
+type ScalingReason int
+
+const (
+	//ScaleForQueueDepth is a resize to meet the TargetQueueDepth
+	ScaleForQueueDepth ScalingReason = iota
+	//ScaleForWait is a resize to meet the TargetWait
+	ScaleForWait
+	//ScaleForBounds is a resize to bring the workers back between MinWorkers and MaxWorkers
+	ScaleForBounds
+)
+
+//String returns the name of the reason
+func (reason ScalingReason) String() string {
+	switch reason {
+	case ScaleForQueueDepth:
+		return "queue depth"
+	case ScaleForWait:
+		return "wait"
+	case ScaleForBounds:
+		return "bounds"
+	}
+	return fmt.Sprintf("ScalingReason(%d)", int(reason))
+}
+
+//ScalingEvent is a scaling decision taken by the autoscaler of a pool, along with the load it was based on
+type ScalingEvent struct {
+	PoolID string
+	At     time.Time
+	//From and To are the amount of workers before and after the decision
+	From   int
+	To     int
+	Reason ScalingReason
+	//QueuedTasks and InFlightTasks are the tasks of the pool at the time of the decision
+	QueuedTasks   int
+	InFlightTasks int
+	//ExpectedWait is the estimated wait of a new task at the time of the decision, 0 if unknown
+	ExpectedWait time.Duration
+}
+
+//desired returns the amount of workers meeting the targets of the policy for the given load and why.
+//The amount is the current one while the load stays within the hysteresis band
+func (policy *AutoscalePolicy) desired(workers, queued, inFlight int, runTime time.Duration) (int, ScalingReason) {
+	load, reason := 0.0, ScaleForQueueDepth
+	known := false
+	if policy.TargetQueueDepth > 0 {
+		load, known = float64(queued)/float64(policy.TargetQueueDepth), true
+	}
+	if policy.TargetWait > 0 {
+		waitLoad := 0.0
+		if runTime > 0 {
+			waitLoad = float64(runTime) * float64(queued+1) / float64(policy.TargetWait)
+		} else if workers == 0 && queued > 0 {
+			// without workers the run time cannot be observed, one is needed to measure it
+			waitLoad = 1
+		}
+		if runTime > 0 || waitLoad > 0 {
+			if !known || waitLoad > load {
+				load, reason = waitLoad, ScaleForWait
+			}
+			known = true
+		}
+	}
+
+	desired := workers
+	switch {
+	case !known:
+	case workers == 0:
+		desired = int(math.Ceil(load))
+	case load/float64(workers) > 1+policy.Hysteresis:
+		desired = int(math.Ceil(load))
+	case load/float64(workers) < 1-policy.Hysteresis:
+		desired = int(math.Ceil(load))
+		// busy workers are not taken away
+		if desired < inFlight {
+			desired = inFlight
+		}
+	}
+	if desired < policy.MinWorkers {
+		desired = policy.MinWorkers
+	}
+	if desired > policy.MaxWorkers {
+		desired = policy.MaxWorkers
+	}
+	if workers < policy.MinWorkers || workers > policy.MaxWorkers {
+		reason = ScaleForBounds
+	}
+	return desired, reason
+}
+
+//autoscaler evaluates the load of a pool every interval and resizes its workers
+type autoscaler struct {
+	policy    AutoscalePolicy
+	pool      pool.Descriptor
+	record    *poolRecord
+	budget    *workerBudget
+	emit      func(ScalingEvent, <-chan struct{})
+	lastScale time.Time
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+func newAutoscaler(policy AutoscalePolicy, descriptor pool.Descriptor, record *poolRecord, budget *workerBudget, emit func(ScalingEvent, <-chan struct{})) *autoscaler {
+	scaler := &autoscaler{policy: policy, pool: descriptor, record: record, budget: budget, emit: emit, stop: make(chan struct{})}
+	go scaler.run()
+	return scaler
+}
+
+func (scaler *autoscaler) run() {
+	interval := scaler.policy.Interval
+	if interval == 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if event, ok := scaler.evaluate(now); ok {
+				scaler.emit(event, scaler.stop)
+			}
+		case <-scaler.stop:
+			return
+		}
+	}
+}
+
+//evaluate resizes the pool if its load is off target and no cooldown is running, it returns the decision taken.
+//Only started pools are resized, once the previous resize has landed. The load is read under the mutex of the record,
+//the resize is done holding only the resizing lock, so tasks keep flowing meanwhile while the pool cannot be stopped
+func (scaler *autoscaler) evaluate(now time.Time) (ScalingEvent, bool) {
+	record := scaler.record
+	record.resizing.Lock()
+	defer record.resizing.Unlock()
+	// the workers of the pool lag behind the amount requested, and the backend resizes relative to them: resizing
+	// before they are there would add or kill workers twice
+	workers := scaler.budget.get(record.poolID)
+	if scaler.pool.GetTotalWorkers() != workers {
+		return ScalingEvent{}, false
+	}
+	record.mutex.Lock()
+	state, runTime := record.state, record.runTime
+	queued, inFlight := record.pending-record.running, record.running
+	record.mutex.Unlock()
+	if state != PoolStarted {
+		return ScalingEvent{}, false
+	}
+	target, reason := scaler.policy.desired(workers, queued, inFlight, runTime)
+	if target == workers {
+		return ScalingEvent{}, false
+	}
+	if reason != ScaleForBounds && !scaler.lastScale.IsZero() {
+		cooldown := scaler.policy.ScaleDownCooldown
+		if target > workers {
+			cooldown = scaler.policy.ScaleUpCooldown
+		}
+		if now.Sub(scaler.lastScale) < cooldown {
+			return ScalingEvent{}, false
+		}
+	}
+	// the pool grows as far as the worker budget allows
+	previous := scaler.budget.get(record.poolID)
+	if target, _ = scaler.budget.request(record.poolID, target, true); target == workers {
+		return ScalingEvent{}, false
+	}
+	if err := scaler.pool.EditWorkersAmount(target); err != nil {
+		scaler.budget.request(record.poolID, previous, true)
+		return ScalingEvent{}, false
+	}
+	scaler.lastScale = now
+	event := ScalingEvent{PoolID: record.poolID, At: now, From: workers, To: target, Reason: reason, QueuedTasks: queued, InFlightTasks: inFlight}
+	if workers > 0 {
+		event.ExpectedWait = runTime * time.Duration(queued+1) / time.Duration(workers)
+	}
+	return event, true
+}
+
+//close stops the autoscaler, the workers of the pool are left as they are
+func (scaler *autoscaler) close() {
+	scaler.stopOnce.Do(func() {
+		close(scaler.stop)
+	})
+}
+
+//SetAutoscale makes the manager resize the workers of poolID following policy while the pool is started,
+//nil disables the autoscaling leaving the workers as they are
+func (manager *Manager) SetAutoscale(poolID string, policy *AutoscalePolicy) error {
+	descriptor, record, ok := manager.lookup(poolID)
+	if !ok {
+		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
+	}
+	if policy != nil {
+		if err := policy.validate(); err != nil {
+			return err
+		}
+	}
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	if err := record.check("autoscale", PoolDefined, PoolStarted, PoolPaused, PoolDraining, PoolStopped); err != nil {
+		return err
+	}
+	record.setAutoscaler(manager, descriptor, policy)
+	return nil
+}
+
+//SetScalingEvents makes the autoscalers send every scaling decision over events, nil stops sending them.
+//Autoscalers block while events is full, so it has to be consumed as long as pools are autoscaled
+func (manager *Manager) SetScalingEvents(events chan<- ScalingEvent) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	manager.scalingEvents = events
+}
+
+//emitScalingEvent sends event over the scaling events channel (if any) unless stop is closed first
+func (manager *Manager) emitScalingEvent(event ScalingEvent, stop <-chan struct{}) {
+	manager.mutex.RLock()
+	events := manager.scalingEvents
+	manager.mutex.RUnlock()
+	if events == nil {
+		return
+	}
+	select {
+	case events <- event:
+	case <-stop:
+	}
+}
+
+//setAutoscaler replaces the autoscaler of the pool by one following policy, it has to be called holding the mutex
+func (record *poolRecord) setAutoscaler(manager *Manager, descriptor pool.Descriptor, policy *AutoscalePolicy) {
+	if record.autoscaler != nil {
+		record.autoscaler.close()
+		record.autoscaler = nil
+	}
+	floor := 0
+	if policy != nil {
+		record.autoscaler = newAutoscaler(*policy, descriptor, record, manager.workerBudget(), manager.emitScalingEvent)
+		floor = policy.MinWorkers
+	}
+	manager.workerBudget().setFloor(record.poolID, floor)
+}
@@ -0,0 +1,217 @@
+package manager
+
+import (
+	"github.com/ericbrisrubio/go-workers-multipool/pool"
+	"testing"
+	"time"
+)
+
+func TestAutoscalePolicy_desired(t *testing.T) {
+	type args struct {
+		workers  int
+		queued   int
+		inFlight int
+		runTime  time.Duration
+	}
+	depth := &AutoscalePolicy{MinWorkers: 1, MaxWorkers: 10, TargetQueueDepth: 2, Hysteresis: 0.2}
+	wait := &AutoscalePolicy{MinWorkers: 0, MaxWorkers: 10, TargetWait: time.Second, Hysteresis: 0.2}
+	tests := []struct {
+		name       string
+		policy     *AutoscalePolicy
+		args       args
+		want       int
+		wantReason ScalingReason
+	}{
+		{"Scales up when the queue is deeper than the target", depth, args{workers: 2, queued: 10}, 5, ScaleForQueueDepth},
+		{"Keeps the workers within the hysteresis band", depth, args{workers: 5, queued: 11}, 5, ScaleForQueueDepth},
+		{"Scales down when the queue is shallower than the target", depth, args{workers: 5, queued: 2}, 1, ScaleForQueueDepth},
+		{"Keeps the busy workers when scaling down", depth, args{workers: 5, queued: 2, inFlight: 4}, 4, ScaleForQueueDepth},
+		{"Is bounded by MaxWorkers", depth, args{workers: 5, queued: 100}, 10, ScaleForQueueDepth},
+		{"Brings the workers back within the bounds", depth, args{workers: 0}, 1, ScaleForBounds},
+		{"Scales up when the wait is longer than the target", wait, args{workers: 2, queued: 7, runTime: time.Millisecond * 500}, 4, ScaleForWait},
+		{"Scales down when the wait is shorter than the target", wait, args{workers: 4, queued: 1, runTime: time.Millisecond * 100}, 1, ScaleForWait},
+		{"Starts a worker to measure the wait", wait, args{workers: 0, queued: 3}, 1, ScaleForWait},
+		{"Keeps the workers while the wait is unknown", wait, args{workers: 3, queued: 3}, 3, ScaleForQueueDepth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := tt.policy.desired(tt.args.workers, tt.args.queued, tt.args.inFlight, tt.args.runTime)
+			if got != tt.want || (got != tt.args.workers && reason != tt.wantReason) {
+				t.Errorf("desired() = %d, %v, want %d, %v", got, reason, tt.want, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestManager_SetAutoscale(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("resize", 1, 10, false)
+	tests := []struct {
+		name    string
+		poolID  string
+		policy  *AutoscalePolicy
+		wantErr bool
+	}{
+		{"Returns error if the pool does not exist", "thumbnails", &AutoscalePolicy{MaxWorkers: 2, TargetQueueDepth: 1}, true},
+		{"Returns error if MaxWorkers is lower than MinWorkers", "resize", &AutoscalePolicy{MinWorkers: 3, MaxWorkers: 2, TargetQueueDepth: 1}, true},
+		{"Returns error without target", "resize", &AutoscalePolicy{MaxWorkers: 2}, true},
+		{"Returns error for a hysteresis out of range", "resize", &AutoscalePolicy{MaxWorkers: 2, TargetQueueDepth: 1, Hysteresis: 1}, true},
+		{"Returns error for a negative cooldown", "resize", &AutoscalePolicy{MaxWorkers: 2, TargetQueueDepth: 1, ScaleUpCooldown: -time.Second}, true},
+		{"Sets the policy", "resize", &AutoscalePolicy{MaxWorkers: 2, TargetQueueDepth: 1}, false},
+		{"Disables the autoscaling", "resize", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := manager.SetAutoscale(tt.poolID, tt.policy); (err != nil) != tt.wantErr {
+				t.Errorf("SetAutoscale() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if manager.recordFor("resize").autoscaler != nil {
+		t.Error("the autoscaler must be stopped once disabled")
+	}
+}
+
+func TestManager_Autoscale(t *testing.T) {
+	manager := createManagerMock(1)
+	events := make(chan ScalingEvent, 10)
+	manager.SetScalingEvents(events)
+	manager.AddPool("resize", 1, 10, false)
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	manager.SetFunc("resize", func(interface{}) bool {
+		started <- struct{}{}
+		<-release
+		return true
+	})
+	manager.StartPool("resize")
+	for i := 0; i < 7; i++ {
+		manager.TrySubmit("resize", i)
+	}
+	<-started
+	// the only worker is busy and 6 tasks are queued once the pool is autoscaled
+	policy := &AutoscalePolicy{MinWorkers: 1, MaxWorkers: 3, TargetQueueDepth: 2, ScaleDownCooldown: time.Millisecond * 50, Interval: time.Millisecond * 5}
+	if err := manager.SetAutoscale("resize", policy); err != nil {
+		t.Fatalf("SetAutoscale() error = %v", err)
+	}
+	next := func() ScalingEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			if event.PoolID != "resize" || event.To < policy.MinWorkers || event.To > policy.MaxWorkers || event.Reason == ScaleForBounds {
+				t.Errorf("scaling event = %+v, out of the bounds of the policy", event)
+			}
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("no scaling event")
+		}
+		return ScalingEvent{}
+	}
+	if event := next(); event.From != 1 || event.To != 3 || event.Reason != ScaleForQueueDepth {
+		t.Errorf("scaling event of the queued tasks = %+v, want from 1 to 3 for the queue depth", event)
+	}
+	if !waitFor(func() bool { stats, _ := manager.PoolStats("resize"); return stats.TotalWorkers == 3 }) {
+		t.Error("the pool must be resized to 3 workers")
+	}
+
+	// once the tasks are over the pool is scaled down to its minimum, maybe in several steps
+	close(release)
+	for next().To != 1 {
+	}
+	if !waitFor(func() bool { stats, _ := manager.PoolStats("resize"); return stats.TotalWorkers == 1 }) {
+		t.Error("the pool must be resized to 1 worker")
+	}
+
+	record := manager.recordFor("resize")
+	manager.StopPool("resize")
+	manager.RemovePool("resize")
+	if record.autoscaler != nil {
+		t.Error("the autoscaler of a removed pool must be stopped")
+	}
+}
+
+func TestAutoscaler_pendingResize(t *testing.T) {
+	descriptor := &pool.GoWorkerPoolFake{}
+	descriptor.AddWorkers(6)
+	record := newPoolRecord("resize")
+	record.state = PoolStarted
+	budget := newWorkerBudget(nil)
+	budget.request("resize", 6, false)
+	scaler := &autoscaler{
+		policy: AutoscalePolicy{MinWorkers: 1, MaxWorkers: 4, TargetQueueDepth: 2},
+		pool:   descriptor,
+		record: record,
+		budget: budget,
+	}
+	if event, ok := scaler.evaluate(time.Now()); !ok || event.From != 6 || event.To != 1 || event.Reason != ScaleForBounds {
+		t.Errorf("evaluate() = %+v, %v, want the pool brought back within the bounds", event, ok)
+	}
+	// the backend has not killed the workers yet
+	descriptor.AddWorkers(5)
+	if event, ok := scaler.evaluate(time.Now()); ok {
+		t.Errorf("evaluate() = %+v while the previous resize is pending", event)
+	}
+	if descriptor.GetTotalWorkers() != 6 || budget.get("resize") != 1 {
+		t.Errorf("a pending resize must be left as is, got %d workers for %d requested", descriptor.GetTotalWorkers(), budget.get("resize"))
+	}
+	descriptor.KillWorkers(5)
+	record.mutex.Lock()
+	record.pending = 6
+	record.mutex.Unlock()
+	if event, ok := scaler.evaluate(time.Now()); !ok || event.From != 1 || event.To != 3 {
+		t.Errorf("evaluate() = %+v, %v once the resize landed", event, ok)
+	}
+}
+
+func TestAutoscaler_cooldown(t *testing.T) {
+	descriptor := &pool.GoWorkerPoolFake{}
+	descriptor.AddWorkers(4)
+	record := newPoolRecord("resize")
+	record.state = PoolStarted
+	budget := newWorkerBudget(nil)
+	budget.request("resize", 4, false)
+	scaler := &autoscaler{
+		policy: AutoscalePolicy{MinWorkers: 1, MaxWorkers: 4, TargetQueueDepth: 1, ScaleUpCooldown: time.Second, ScaleDownCooldown: time.Minute},
+		pool:   descriptor,
+		record: record,
+		budget: budget,
+	}
+	now := time.Now()
+	scaler.lastScale = now.Add(-time.Second * 30)
+	if _, ok := scaler.evaluate(now); ok {
+		t.Error("the pool must not be scaled down during the cooldown")
+	}
+	if _, ok := scaler.evaluate(now.Add(time.Minute)); !ok {
+		t.Error("the pool must be scaled down once the cooldown is over")
+	}
+	record.mutex.Lock()
+	record.pending = 3
+	record.mutex.Unlock()
+	if _, ok := scaler.evaluate(now.Add(time.Minute + time.Millisecond*500)); ok {
+		t.Error("the pool must not be scaled up during the cooldown")
+	}
+	if event, ok := scaler.evaluate(now.Add(time.Minute * 2)); !ok || event.To != 3 {
+		t.Errorf("evaluate() = %+v, %v once the cooldown is over", event, ok)
+	}
+}
+
+func TestAutoscaler_budget(t *testing.T) {
+	descriptor := &pool.GoWorkerPoolFake{}
+	record := newPoolRecord("resize")
+	record.state = PoolStarted
+	record.pending = 8
+	budget := newWorkerBudget(nil)
+	budget.total = 2
+	scaler := &autoscaler{
+		policy: AutoscalePolicy{MaxWorkers: 4, TargetQueueDepth: 2},
+		pool:   descriptor,
+		record: record,
+		budget: budget,
+	}
+	if event, ok := scaler.evaluate(time.Now()); !ok || event.To != 2 || descriptor.GetTotalWorkers() != 2 {
+		t.Errorf("evaluate() = %+v, %v, want the pool bounded by the budget", event, ok)
+	}
+	if _, ok := scaler.evaluate(time.Now()); ok {
+		t.Error("the pool must not be resized once the budget is exhausted")
+	}
+}
@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"github.com/ericbrisrubio/go-workers-multipool/pool"
+	"sync/atomic"
+	"testing"
+)
+
+func TestManager_AddPoolWithBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		wantErr bool
+	}{
+		{"Uses goworkerpool by default", "", false},
+		{"Uses a registered backend", pool.ChannelBackend, false},
+		{"Returns error if the backend is not registered", "unknown", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := createManagerMock(1)
+			err := manager.AddPoolWithOptions("imageProcessing", PoolOptions{InitialWorkers: 1, MaxJobsInQueue: 10, Backend: tt.backend})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AddPoolWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManager_Backends(t *testing.T) {
+	for _, backend := range pool.Backends() {
+		t.Run(backend, func(t *testing.T) {
+			manager := createManagerMock(1)
+			manager.AddPoolWithOptions("imageProcessing", PoolOptions{InitialWorkers: 2, MaxJobsInQueue: 10, Backend: backend})
+			var processed int64
+			manager.SetFunc("imageProcessing", func(interface{}) bool {
+				atomic.AddInt64(&processed, 1)
+				return true
+			})
+			if err := manager.StartPool("imageProcessing"); err != nil {
+				t.Fatalf("StartPool() error = %v", err)
+			}
+			manager.PauseWorkersFromPool("imageProcessing")
+			for i := 0; i < 5; i++ {
+				if err := manager.AddTaskToPool("imageProcessing", i); err != nil {
+					t.Fatalf("AddTaskToPool() error = %v", err)
+				}
+			}
+			manager.ResumeWorkersFromPool("imageProcessing")
+			if err := manager.StopPool("imageProcessing"); err != nil {
+				t.Fatalf("StopPool() error = %v", err)
+			}
+			if atomic.LoadInt64(&processed) != 5 {
+				t.Errorf("processed %d tasks, want 5", processed)
+			}
+			if err := manager.RestartPool("imageProcessing"); err != nil {
+				t.Fatalf("RestartPool() error = %v", err)
+			}
+			manager.AddTaskToPool("imageProcessing", 5)
+			manager.StopPool("imageProcessing")
+			if stats, _ := manager.PoolStats("imageProcessing"); stats.CompletedTasks != 6 {
+				t.Errorf("CompletedTasks = %d once restarted, want 6", stats.CompletedTasks)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"time"
+)
+
+//ErrQueueFull is returned when a task is submitted to a pool whose queue has no room left
+var ErrQueueFull = errors.New("queue of the pool is full")
+
+//TrySubmit enqueues a new task to be accomplished by the desired pool without waiting, it returns ErrQueueFull
+//if the queue of the pool has no room left
+func (manager *Manager) TrySubmit(poolID string, data interface{}) error {
+	if data == nil {
+		return errors.New("data cannot be nil")
+	}
+	return manager.enqueue(poolID, &task{ctx: context.Background(), data: data})
+}
+
+//SubmitWithTimeout enqueues a new task to be accomplished by the desired pool, waiting up to timeout for room
+//in its queue. It returns ErrQueueFull if the queue is still full once the timeout is over
+func (manager *Manager) SubmitWithTimeout(poolID string, data interface{}, timeout time.Duration) error {
+	if data == nil {
+		return errors.New("data cannot be nil")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return manager.enqueueWait(ctx, poolID, &task{ctx: context.Background(), data: data})
+}
+
+//SubmitContext enqueues a new task to be accomplished by the desired pool, waiting for room in its queue until ctx is done.
+//It returns ErrQueueFull if the queue is still full by then. Cancelling ctx also cancels the task, as AddTaskToPoolContext does
+func (manager *Manager) SubmitContext(ctx context.Context, poolID string, data interface{}) error {
+	if ctx == nil {
+		return errors.New("ctx cannot be nil")
+	}
+	if data == nil {
+		return errors.New("data cannot be nil")
+	}
+	return manager.untyped(poolID).SubmitContext(ctx, data)
+}
+
+//enqueue adds task to the queue of poolID, or to one of its fallbacks if poolID is saturated.
+//It returns ErrQueueFull if the queue has no room left
+func (manager *Manager) enqueue(poolID string, task *task) error {
+	_, err := manager.tryEnqueue(poolID, task)
+	return err
+}
+
+//enqueueWait adds task to the queue of poolID waiting for room in it until ctx is done
+func (manager *Manager) enqueueWait(ctx context.Context, poolID string, task *task) error {
+	for {
+		room, err := manager.tryEnqueue(poolID, task)
+		if err != ErrQueueFull {
+			return err
+		}
+		select {
+		case <-room:
+		case <-ctx.Done():
+			return ErrQueueFull
+		}
+	}
+}
+
+//tryEnqueue adds task to the queue of poolID, or to one of its fallbacks if poolID is saturated.
+//If the queue is full it returns ErrQueueFull along with a channel closed once the queue may have room again
+func (manager *Manager) tryEnqueue(poolID string, task *task) (<-chan struct{}, error) {
+	pool, record, ok := manager.lookup(poolID)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("No pool exists for poolID: %s", poolID))
+	}
+	// keyed and replayed tasks stick to their pool
+	replayed := task.durableID != 0
+	var spilledFrom *poolRecord
+	if task.key == "" && !replayed {
+		pool, record, spilledFrom = manager.spill(poolID, pool, record)
+	}
+	if err := record.accepts(task.data); err != nil {
+		return nil, err
+	}
+	room := record.roomChan()
+	poolCtx, err := record.acquire()
+	if err != nil {
+		return room, err
+	}
+	task.poolCtx = poolCtx
+	task.attempt = 1
+	task.submittedAt = time.Now()
+	task.timeout = taskTimeout(task.ctx)
+	if record.durable != nil && !replayed {
+		if task.durableID, err = record.durable.persist(task.data); err != nil {
+			record.reject()
+			return nil, err
+		}
+	}
+	if task.key != "" && !record.keys.claim(task) {
+		// the task is handed to the pool once the previous task of its key is over
+		return nil, nil
+	}
+	if err := record.dispatch(pool, task); err != nil {
+		task.discard()
+		record.reject()
+		// the submitter of a new task gets the error, a replayed one is kept for the next replay
+		outcome := outcomeFailed
+		if replayed {
+			outcome = outcomeCancelled
+		}
+		record.conclude(pool, task, outcome)
+		return nil, err
+	}
+	if spilledFrom != nil {
+		spilledFrom.countSpill()
+	}
+	return nil, nil
+}
+
+//roomChan returns a channel closed once a task leaves the queue of the pool or the pool changes its state
+func (record *poolRecord) roomChan() <-chan struct{} {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	return record.room
+}
+
+//vacate wakes up the submissions waiting for room in the queue, it has to be called holding the mutex
+func (record *poolRecord) vacate() {
+	close(record.room)
+	record.room = make(chan struct{})
+}
@@ -0,0 +1,141 @@
+package manager
+
+import (
+	"context"
+	"github.com/ericbrisrubio/go-workers-multipool/pool"
+	"github.com/pkg/errors"
+	"testing"
+	"time"
+)
+
+func TestManager_TrySubmit(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 1, 2, false)
+	manager.SetFunc("slowProcessing", func(interface{}) bool { return true })
+	tests := []struct {
+		name    string
+		data    interface{}
+		wantErr error
+	}{
+		{"Enqueues a task while the queue has room", "first", nil},
+		{"Enqueues a task filling the queue", "second", nil},
+		{"Returns ErrQueueFull once the queue is full", "third", ErrQueueFull},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := manager.TrySubmit("slowProcessing", tt.data); err != tt.wantErr {
+				t.Errorf("TrySubmit() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	if err := manager.AddTaskToPoolContext(ctx, "slowProcessing", "fourth"); err != ErrQueueFull {
+		t.Errorf("AddTaskToPoolContext() on a full queue error = %v, want %v", err, ErrQueueFull)
+	}
+	manager.StartPool("slowProcessing")
+	manager.StopPool("slowProcessing")
+	if stats, _ := manager.PoolStats("slowProcessing"); stats.CompletedTasks != 2 {
+		t.Errorf("expected the 2 accepted tasks to be processed, got %+v", stats)
+	}
+}
+
+func TestManager_AddTaskToPoolWaitsForRoom(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 1, 1, false)
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	manager.SetFunc("slowProcessing", func(interface{}) bool {
+		started <- struct{}{}
+		<-release
+		return true
+	})
+	manager.StartPool("slowProcessing")
+	manager.AddTaskToPool("slowProcessing", "first")
+	<-started
+	manager.AddTaskToPool("slowProcessing", "second")
+
+	added := make(chan error, 1)
+	go func() {
+		added <- manager.AddTaskToPool("slowProcessing", "third")
+	}()
+	select {
+	case err := <-added:
+		t.Fatalf("AddTaskToPool() on a full queue returned %v instead of waiting for room", err)
+	case <-time.After(time.Millisecond * 20):
+	}
+	close(release)
+	select {
+	case err := <-added:
+		if err != nil {
+			t.Errorf("AddTaskToPool() once the queue has room error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("AddTaskToPool() is still waiting once the queue has room")
+	}
+}
+
+func TestManager_SubmitWithTimeout(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 1, 1, false)
+	manager.SetFunc("slowProcessing", func(interface{}) bool { return true })
+	manager.TrySubmit("slowProcessing", "first")
+
+	start := time.Now()
+	if err := manager.SubmitWithTimeout("slowProcessing", "second", time.Millisecond*20); err != ErrQueueFull {
+		t.Errorf("SubmitWithTimeout() on a full queue error = %v, want %v", err, ErrQueueFull)
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*20 {
+		t.Errorf("SubmitWithTimeout() returned after %v, before the timeout", elapsed)
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		manager.StartPool("slowProcessing")
+	}()
+	if err := manager.SubmitWithTimeout("slowProcessing", "second", time.Second); err != nil {
+		t.Errorf("SubmitWithTimeout() once the queue has room error = %v", err)
+	}
+	manager.StopPool("slowProcessing")
+	if stats, _ := manager.PoolStats("slowProcessing"); stats.CompletedTasks != 2 {
+		t.Errorf("expected 2 processed tasks, got %+v", stats)
+	}
+}
+
+func TestManager_SubmitContext(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 1, 1, false)
+	manager.SetFunc("slowProcessing", func(interface{}) bool { return true })
+	manager.TrySubmit("slowProcessing", "first")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	if err := manager.SubmitContext(ctx, "slowProcessing", "second"); err != ErrQueueFull {
+		t.Errorf("SubmitContext() on a full queue error = %v, want %v", err, ErrQueueFull)
+	}
+	if err := manager.SubmitContext(context.Background(), "fastProcessing", "second"); err == nil {
+		t.Error("SubmitContext() must fail for a pool not defined")
+	}
+	manager.StartPool("slowProcessing")
+	manager.StopPool("slowProcessing")
+	if err := manager.SubmitContext(context.Background(), "slowProcessing", "second"); err == nil {
+		t.Error("SubmitContext() must fail for a pool that does not accept tasks")
+	}
+}
+
+func TestManager_AddTaskToPoolPropagatesError(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 1, 10, false)
+	errDispatcher := errors.New("dispatcher channel full")
+	manager.pools["slowProcessing"] = &pool.GoWorkerPoolFake{AddTaskError: errDispatcher}
+	manager.StartPool("slowProcessing")
+	if err := manager.AddTaskToPool("slowProcessing", "task"); err != errDispatcher {
+		t.Errorf("AddTaskToPool() error = %v, want %v", err, errDispatcher)
+	}
+	if stats, _ := manager.PoolStats("slowProcessing"); stats.QueuedTasks != 0 || stats.InFlightTasks != 0 {
+		t.Errorf("a rejected task must not be pending, got %+v", stats)
+	}
+	if err := manager.StopPool("slowProcessing"); err != nil {
+		t.Errorf("StopPool() after a rejected task error = %v", err)
+	}
+}
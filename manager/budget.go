@@ -0,0 +1,333 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+	"sync"
+)
+
+//ErrBudgetExceeded is returned when the workers requested for a pool do not fit in the worker budget of the manager
+var ErrBudgetExceeded = errors.New("worker budget exceeded")
+
+//BudgetShare is the part of the worker budget of the manager a pool is entitled to
+type BudgetShare struct {
+	//Min is the amount of workers guaranteed to the pool, capacity lent to other pools is reclaimed to honor it
+	Min int
+	//Weight is how much of the budget left above the guaranteed minimums the pool gets when pools compete for it,
+	//relative to the weight of the other pools. 0 means 1
+	Weight int
+}
+
+func (share BudgetShare) validate() error {
+	if share.Min < 0 {
+		return errors.New("budget Min has to be greater or equal to 0")
+	}
+	if share.Weight < 0 {
+		return errors.New("budget Weight has to be greater or equal to 0")
+	}
+	return nil
+}
+
+func (share BudgetShare) weight() int {
+	if share.Weight == 0 {
+		return 1
+	}
+	return share.Weight
+}
+
+//PoolBudgetUsage is a snapshot of the part of the worker budget used by a pool
+type PoolBudgetUsage struct {
+	PoolID string
+	BudgetShare
+	//Allocated is the amount of workers the pool has
+	Allocated int
+	//FairShare is the amount of workers the pool is entitled to while every pool with workers competes for the budget,
+	//a pool can get more by borrowing the capacity the others leave idle
+	FairShare int
+}
+
+//BudgetUsage is a snapshot of the worker budget of the manager
+type BudgetUsage struct {
+	//Total is the worker budget, 0 means the workers are not bounded
+	Total     int
+	Allocated int
+	Pools     []PoolBudgetUsage
+}
+
+//workerBudget keeps the amount of workers of every pool and bounds their sum. The amounts are the ones
+//requested by the manager, as the pools apply them asynchronously
+type workerBudget struct {
+	mutex     sync.Mutex
+	total     int
+	shares    map[string]BudgetShare
+	allocated map[string]int
+	//floors are the workers the pools keep whatever they lent, the minimum of their autoscale policy
+	floors map[string]int
+	//shrink applies to a pool the capacity reclaimed from it, it is called without holding the mutex
+	shrink func(reclaimed loan)
+}
+
+//loan is capacity of the budget a pool borrowed and has been reclaimed from it
+type loan struct {
+	poolID string
+	taken  int
+}
+
+func newWorkerBudget(shrink func(reclaimed loan)) *workerBudget {
+	return &workerBudget{
+		shares:    make(map[string]BudgetShare),
+		allocated: make(map[string]int),
+		floors:    make(map[string]int),
+		shrink:    shrink,
+	}
+}
+
+//guaranteed returns the sum of the guaranteed minimums, replacing the one of poolID by share
+func (budget *workerBudget) guaranteed(poolID string, share BudgetShare) int {
+	sum := share.Min
+	for id, other := range budget.shares {
+		if id != poolID {
+			sum += other.Min
+		}
+	}
+	return sum
+}
+
+//fairShares returns the fair share of the pools with workers and of poolID, it has to be called holding the mutex
+func (budget *workerBudget) fairShares(poolID string) map[string]int {
+	competing := map[string]bool{poolID: true}
+	for id, allocated := range budget.allocated {
+		if allocated > 0 {
+			competing[id] = true
+		}
+	}
+	spare, weights := budget.total, 0
+	for id := range competing {
+		spare -= budget.shares[id].Min
+		weights += budget.shares[id].weight()
+	}
+	if spare < 0 {
+		spare = 0
+	}
+	fair := make(map[string]int, len(competing))
+	for id := range competing {
+		fair[id] = budget.shares[id].Min + spare*budget.shares[id].weight()/weights
+	}
+	return fair
+}
+
+//request sets the workers of poolID to amount and returns the amount granted. Growing a pool takes the capacity
+//left free first and then reclaims, up to the fair share of poolID, the capacity other pools borrowed above theirs.
+//Unless partial is set, ErrBudgetExceeded is returned without changing anything if amount cannot be granted entirely
+func (budget *workerBudget) request(poolID string, amount int, partial bool) (int, error) {
+	budget.mutex.Lock()
+	granted, reclaimed, err := budget.allocate(poolID, amount, partial)
+	budget.mutex.Unlock()
+	budget.giveBack(reclaimed)
+	return granted, err
+}
+
+//add changes the workers of poolID by delta, see request
+func (budget *workerBudget) add(poolID string, delta int, partial bool) (int, error) {
+	budget.mutex.Lock()
+	amount := budget.allocated[poolID] + delta
+	if amount < 0 {
+		amount = 0
+	}
+	granted, reclaimed, err := budget.allocate(poolID, amount, partial)
+	budget.mutex.Unlock()
+	budget.giveBack(reclaimed)
+	return granted, err
+}
+
+//giveBack shrinks the pools whose capacity was reclaimed. It runs in the background as shrinking a pool goes
+//through its record, whose locks can be held by the caller of request
+func (budget *workerBudget) giveBack(reclaimed []loan) {
+	for _, lent := range reclaimed {
+		go budget.shrink(lent)
+	}
+}
+
+//restore gives back to poolID the capacity reclaimed from it that it could not give up
+func (budget *workerBudget) restore(lent loan) {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	if _, ok := budget.allocated[lent.poolID]; ok {
+		budget.allocated[lent.poolID] += lent.taken
+	}
+}
+
+//setFloor makes poolID keep at least floor workers when its capacity is reclaimed
+func (budget *workerBudget) setFloor(poolID string, floor int) {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	budget.floors[poolID] = floor
+}
+
+//allocate implements request, it has to be called holding the mutex. The capacity reclaimed from other pools
+//is only accounted, the pools have to be shrunk accordingly once the mutex is released
+func (budget *workerBudget) allocate(poolID string, amount int, partial bool) (int, []loan, error) {
+	current := budget.allocated[poolID]
+	if budget.total == 0 || amount <= current {
+		budget.allocated[poolID] = amount
+		return amount, nil, nil
+	}
+	used := 0
+	for _, allocated := range budget.allocated {
+		used += allocated
+	}
+	granted := current
+	if free := budget.total - used; free > 0 {
+		granted += free
+	}
+	if granted >= amount {
+		budget.allocated[poolID] = amount
+		return amount, nil, nil
+	}
+
+	fair := budget.fairShares(poolID)
+	type borrower struct {
+		poolID string
+		excess int
+	}
+	borrowers, reclaimable := []borrower{}, 0
+	for id, allocated := range budget.allocated {
+		share, ok := fair[id]
+		if !ok || id == poolID {
+			continue
+		}
+		// autoscaled pools are not shrunk below their minimum
+		if floor := budget.floors[id]; floor > share {
+			share = floor
+		}
+		if allocated > share {
+			borrowers = append(borrowers, borrower{id, allocated - share})
+			reclaimable += allocated - share
+		}
+	}
+	possible := granted
+	if want := minInt(amount, fair[poolID]); want > granted {
+		possible = minInt(want, granted+reclaimable)
+	}
+	if possible < amount && !partial {
+		return current, nil, ErrBudgetExceeded
+	}
+	// the biggest borrowers give their capacity back first
+	sort.Slice(borrowers, func(i, j int) bool {
+		return borrowers[i].excess > borrowers[j].excess
+	})
+	reclaimed := []loan{}
+	for need, i := possible-granted, 0; need > 0 && i < len(borrowers); i++ {
+		taken := minInt(need, borrowers[i].excess)
+		budget.allocated[borrowers[i].poolID] -= taken
+		reclaimed = append(reclaimed, loan{poolID: borrowers[i].poolID, taken: taken})
+		need -= taken
+	}
+	budget.allocated[poolID] = possible
+	return possible, reclaimed, nil
+}
+
+//forget removes poolID from the budget
+func (budget *workerBudget) forget(poolID string) {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	delete(budget.shares, poolID)
+	delete(budget.allocated, poolID)
+	delete(budget.floors, poolID)
+}
+
+func (budget *workerBudget) get(poolID string) int {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	return budget.allocated[poolID]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+//workerBudget returns the worker budget of the manager, creating it the first time
+func (manager *Manager) workerBudget() *workerBudget {
+	manager.budgetOnce.Do(func() {
+		manager.budget = newWorkerBudget(manager.shrinkLender)
+	})
+	return manager.budget
+}
+
+//shrinkLender applies to a pool the capacity the worker budget reclaimed from it, holding its resizing lock so it
+//cannot be stopped meanwhile. A draining pool keeps its workers to process its queue, the capacity is given back
+//to it in the budget until it is stopped
+func (manager *Manager) shrinkLender(lent loan) {
+	descriptor, record, ok := manager.lookup(lent.poolID)
+	if !ok {
+		return
+	}
+	record.resizing.Lock()
+	defer record.resizing.Unlock()
+	budget := manager.workerBudget()
+	switch record.getState() {
+	case PoolStarted, PoolPaused:
+		descriptor.EditWorkersAmount(budget.get(lent.poolID))
+	case PoolDraining:
+		budget.restore(lent)
+	}
+}
+
+//SetWorkerBudget bounds the sum of the workers of every pool to total, 0 means they are not bounded.
+//Pools already above their part of a lower budget keep their workers until they are resized
+func (manager *Manager) SetWorkerBudget(total int) error {
+	if total < 0 {
+		return errors.New("budget total has to be greater or equal to 0")
+	}
+	budget := manager.workerBudget()
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	if guaranteed := budget.guaranteed("", BudgetShare{}); total > 0 && guaranteed > total {
+		return errors.New(fmt.Sprintf("the guaranteed minimums (%d workers) do not fit in a budget of %d workers", guaranteed, total))
+	}
+	budget.total = total
+	return nil
+}
+
+//SetPoolBudget defines the part of the worker budget poolID is entitled to
+func (manager *Manager) SetPoolBudget(poolID string, share BudgetShare) error {
+	if _, ok := manager.getPool(poolID); !ok {
+		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
+	}
+	return manager.workerBudget().setShare(poolID, share)
+}
+
+func (budget *workerBudget) setShare(poolID string, share BudgetShare) error {
+	if err := share.validate(); err != nil {
+		return err
+	}
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	if guaranteed := budget.guaranteed(poolID, share); budget.total > 0 && guaranteed > budget.total {
+		return errors.New(fmt.Sprintf("the guaranteed minimums (%d workers) do not fit in a budget of %d workers", guaranteed, budget.total))
+	}
+	budget.shares[poolID] = share
+	return nil
+}
+
+//WorkerBudget returns how the worker budget is used, pools sorted by id
+func (manager *Manager) WorkerBudget() BudgetUsage {
+	poolIDs := manager.ListPools()
+	budget := manager.workerBudget()
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	usage := BudgetUsage{Total: budget.total, Pools: []PoolBudgetUsage{}}
+	for _, poolID := range poolIDs {
+		poolUsage := PoolBudgetUsage{PoolID: poolID, BudgetShare: budget.shares[poolID], Allocated: budget.allocated[poolID]}
+		if budget.total > 0 {
+			poolUsage.FairShare = budget.fairShares(poolID)[poolID]
+		}
+		usage.Allocated += poolUsage.Allocated
+		usage.Pools = append(usage.Pools, poolUsage)
+	}
+	return usage
+}
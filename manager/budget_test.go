@@ -0,0 +1,163 @@
+package manager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManager_SetWorkerBudget(t *testing.T) {
+	manager := createManagerMock(2)
+	manager.AddPool("reports", 1, 10, false)
+	manager.AddPoolWithOptions("emails", PoolOptions{MaxJobsInQueue: 10, Budget: &BudgetShare{Min: 3}})
+	tests := []struct {
+		name    string
+		apply   func() error
+		wantErr bool
+	}{
+		{"Returns error for a negative budget", func() error { return manager.SetWorkerBudget(-1) }, true},
+		{"Returns error if the guaranteed minimums do not fit", func() error { return manager.SetWorkerBudget(2) }, true},
+		{"Sets the budget", func() error { return manager.SetWorkerBudget(5) }, false},
+		{"Returns error for a negative weight", func() error { return manager.SetPoolBudget("reports", BudgetShare{Weight: -1}) }, true},
+		{"Returns error if the pool does not exist", func() error { return manager.SetPoolBudget("invoices", BudgetShare{Min: 1}) }, true},
+		{"Returns error if the new minimum does not fit", func() error { return manager.SetPoolBudget("reports", BudgetShare{Min: 3}) }, true},
+		{"Sets the share of the pool", func() error { return manager.SetPoolBudget("reports", BudgetShare{Min: 2, Weight: 2}) }, false},
+		{"Returns error for a pool whose minimum does not fit", func() error {
+			return manager.AddPoolWithOptions("invoices", PoolOptions{MaxJobsInQueue: 10, Budget: &BudgetShare{Min: 1}})
+		}, true},
+		{"Disables the budget", func() error { return manager.SetWorkerBudget(0) }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.apply(); (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if _, ok := manager.getPool("invoices"); ok {
+		t.Error("a pool whose minimum does not fit must not be added")
+	}
+}
+
+func TestManager_WorkerBudget(t *testing.T) {
+	manager := createManagerMock(2)
+	manager.SetWorkerBudget(6)
+	manager.AddPoolWithOptions("reports", PoolOptions{InitialWorkers: 5, MaxJobsInQueue: 10, Budget: &BudgetShare{Min: 2}})
+	manager.AddPoolWithOptions("emails", PoolOptions{InitialWorkers: 4, MaxJobsInQueue: 10, Budget: &BudgetShare{Min: 2}})
+	allocated := func() map[string]int {
+		workers := map[string]int{}
+		for _, usage := range manager.WorkerBudget().Pools {
+			workers[usage.PoolID] = usage.Allocated
+		}
+		return workers
+	}
+
+	manager.StartPool("reports")
+	if workers := allocated(); workers["reports"] != 5 {
+		t.Errorf("reports borrowing the idle capacity got %d workers, want 5", workers["reports"])
+	}
+	manager.StartPool("emails")
+	if workers := allocated(); workers["reports"] != 3 || workers["emails"] != 3 {
+		t.Errorf("workers once emails reclaimed its fair share = %v, want 3 each", workers)
+	}
+	if !waitFor(func() bool { stats, _ := manager.PoolStats("reports"); return stats.TotalWorkers == 3 }) {
+		t.Error("the capacity reclaimed from reports must be applied to the pool")
+	}
+
+	if err := manager.AddWorkersToPool("emails", 1); err != ErrBudgetExceeded {
+		t.Errorf("AddWorkersToPool() above the budget error = %v, want ErrBudgetExceeded", err)
+	}
+	if err := manager.KillWorkersFromPool("reports", 1); err != nil {
+		t.Errorf("KillWorkersFromPool() error = %v", err)
+	}
+	if err := manager.AddWorkersToPool("emails", 1); err != nil {
+		t.Errorf("AddWorkersToPool() within the budget error = %v", err)
+	}
+
+	manager.StopPool("emails")
+	if err := manager.EditPoolWorkersAmount("reports", 6); err != nil {
+		t.Errorf("EditPoolWorkersAmount() borrowing the capacity of a stopped pool error = %v", err)
+	}
+	if err := manager.EditPoolWorkersAmount("reports", 7); err != ErrBudgetExceeded {
+		t.Errorf("EditPoolWorkersAmount() above the budget error = %v, want ErrBudgetExceeded", err)
+	}
+	usage := manager.WorkerBudget()
+	if usage.Total != 6 || usage.Allocated != 6 || usage.Pools[1].PoolID != "reports" || usage.Pools[1].FairShare != 6 {
+		t.Errorf("WorkerBudget() = %+v", usage)
+	}
+
+	manager.StopPool("reports")
+	manager.RemovePool("reports")
+	if workers := allocated(); len(workers) != 1 || workers["emails"] != 0 {
+		t.Errorf("workers once reports is removed = %v", workers)
+	}
+}
+
+func TestManager_WorkerBudgetReclaim(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       func(manager *Manager)
+		wantReports int
+		wantEmails  int
+	}{
+		{
+			"Reclaims the capacity borrowed above the fair share",
+			func(manager *Manager) {},
+			3,
+			3,
+		},
+		{
+			"Does not shrink an autoscaled pool below its minimum",
+			func(manager *Manager) {
+				manager.SetAutoscale("reports", &AutoscalePolicy{MinWorkers: 4, MaxWorkers: 5, TargetQueueDepth: 1, Interval: time.Hour})
+			},
+			4,
+			2,
+		},
+		{
+			"Does not shrink a draining pool",
+			func(manager *Manager) {
+				manager.AddTaskToPool("reports", "report-1")
+				go manager.StopPool("reports")
+				waitFor(func() bool { stats, _ := manager.PoolStats("reports"); return stats.State == PoolDraining })
+			},
+			5,
+			3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := createManagerMock(2)
+			manager.SetWorkerBudget(6)
+			manager.AddPoolWithOptions("reports", PoolOptions{InitialWorkers: 5, MaxJobsInQueue: 10, Budget: &BudgetShare{Min: 2}})
+			manager.AddPoolWithOptions("emails", PoolOptions{InitialWorkers: 4, MaxJobsInQueue: 10, Budget: &BudgetShare{Min: 2}})
+			release := make(chan struct{})
+			defer close(release)
+			manager.SetFunc("reports", func(interface{}) bool {
+				<-release
+				return true
+			})
+			manager.StartPool("reports")
+			tt.setup(manager)
+			manager.StartPool("emails")
+
+			workers := func(poolID string) int {
+				stats, _ := manager.PoolStats(poolID)
+				return stats.TotalWorkers
+			}
+			allocated := func(poolID string) int {
+				for _, usage := range manager.WorkerBudget().Pools {
+					if usage.PoolID == poolID {
+						return usage.Allocated
+					}
+				}
+				return 0
+			}
+			if !waitFor(func() bool { return workers("reports") == tt.wantReports && workers("emails") == tt.wantEmails }) {
+				t.Errorf("workers of reports and emails = %d and %d, want %d and %d", workers("reports"), workers("emails"), tt.wantReports, tt.wantEmails)
+			}
+			if !waitFor(func() bool { return allocated("reports") == tt.wantReports }) {
+				t.Errorf("workers allocated to reports = %d, want %d", allocated("reports"), tt.wantReports)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/ericbrisrubio/go-workers-multipool/pool"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// These tests are meant to be executed with the race detector: go test -race ./...
+
+func TestManager_ConcurrentAddPoolAndAddTask(t *testing.T) {
+	manager := createManagerMock(1)
+	if err := manager.AddPool("slowProcessing", 2, 100, false); err != nil {
+		t.Fatalf("AddPool() error = %v", err)
+	}
+	var processed int64
+	manager.SetFunc("slowProcessing", func(data interface{}) bool {
+		atomic.AddInt64(&processed, 1)
+		return true
+	})
+	if err := manager.StartPool("slowProcessing"); err != nil {
+		t.Fatalf("StartPool() error = %v", err)
+	}
+
+	waitGroup := new(sync.WaitGroup)
+	for i := 0; i < 10; i++ {
+		waitGroup.Add(2)
+		go func(producer int) {
+			defer waitGroup.Done()
+			for j := 0; j < 20; j++ {
+				if err := manager.AddTaskToPool("slowProcessing", fmt.Sprintf("task %d-%d", producer, j)); err != nil {
+					t.Errorf("AddTaskToPool() error = %v", err)
+				}
+			}
+		}(i)
+		go func(admin int) {
+			defer waitGroup.Done()
+			if err := manager.AddPool(fmt.Sprintf("pool-%d", admin), 0, 10, false); err != nil {
+				t.Errorf("AddPool() error = %v", err)
+			}
+		}(i)
+	}
+	waitGroup.Wait()
+
+	if len(manager.pools) != 11 || len(manager.poolsInitializer) != 11 {
+		t.Errorf("expected 11 pools, got %d pools and %d initializers", len(manager.pools), len(manager.poolsInitializer))
+	}
+}
+
+func TestManager_ConcurrentAddPoolWithSameID(t *testing.T) {
+	manager := &Manager{}
+	var created int64
+	waitGroup := new(sync.WaitGroup)
+	for i := 0; i < 20; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			if manager.AddPool("slowProcessing", 1, 10, false) == nil {
+				atomic.AddInt64(&created, 1)
+			}
+		}()
+	}
+	waitGroup.Wait()
+	if created != 1 {
+		t.Errorf("expected exactly one pool to be created, got %d", created)
+	}
+}
+
+func TestManager_ConcurrentPoolOperations(t *testing.T) {
+	manager := createManagerMock(4)
+	fakes := make([]*pool.GoWorkerPoolFake, 4)
+	for i := range fakes {
+		poolID := fmt.Sprintf("pool-%d", i)
+		manager.AddPool(poolID, 1, 10, false)
+		fakes[i] = &pool.GoWorkerPoolFake{}
+		manager.pools[poolID] = fakes[i]
+		manager.StartPool(poolID)
+	}
+
+	waitGroup := new(sync.WaitGroup)
+	for i := 0; i < 50; i++ {
+		waitGroup.Add(1)
+		go func(iteration int) {
+			defer waitGroup.Done()
+			poolID := fmt.Sprintf("pool-%d", iteration%len(fakes))
+			operations := []func() error{
+				func() error { return manager.SetFunc(poolID, func(interface{}) bool { return true }) },
+				func() error { return manager.AddTaskToPool(poolID, iteration) },
+				func() error { return manager.AddWorkersToPool(poolID, 1) },
+				func() error { return manager.KillWorkersFromPool(poolID, 1) },
+				func() error { return manager.EditPoolWorkersAmount(poolID, 2) },
+				func() error { return manager.PauseWorkersFromPool(poolID) },
+				func() error { return manager.ResumeWorkersFromPool(poolID) },
+				func() error { return manager.WaitForPool(poolID) },
+				func() error { return manager.WaitForAllPools() },
+				func() error { return manager.AddPool(fmt.Sprintf("extra-%d", iteration), 0, 1, false) },
+			}
+			for _, operation := range operations {
+				if err := operation(); err != nil {
+					t.Errorf("operation on %s failed: %v", poolID, err)
+				}
+			}
+		}(i)
+	}
+	waitGroup.Wait()
+}
+
+func TestManager_ConcurrentWaitForAllPoolsWhileAdding(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 1, 10, false)
+	manager.pools["slowProcessing"] = &pool.GoWorkerPoolFake{}
+
+	waitGroup := new(sync.WaitGroup)
+	for i := 0; i < 10; i++ {
+		waitGroup.Add(2)
+		go func() {
+			defer waitGroup.Done()
+			if err := manager.WaitForAllPools(); err != nil {
+				t.Errorf("WaitForAllPools() error = %v", err)
+			}
+		}()
+		go func(admin int) {
+			defer waitGroup.Done()
+			manager.AddPool(fmt.Sprintf("pool-%d", admin), 0, 10, false)
+		}(i)
+	}
+	waitGroup.Wait()
+}
+
+func TestManager_ConcurrentStopPool(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 2, 10, false)
+	manager.pools["slowProcessing"] = &pool.GoWorkerPoolFake{}
+	manager.SetFunc("slowProcessing", func(interface{}) bool { return true })
+	manager.StartPool("slowProcessing")
+
+	var stopped int64
+	waitGroup := new(sync.WaitGroup)
+	for i := 0; i < 20; i++ {
+		waitGroup.Add(2)
+		go func() {
+			defer waitGroup.Done()
+			if manager.StopPool("slowProcessing") == nil {
+				atomic.AddInt64(&stopped, 1)
+			}
+		}()
+		go func(iteration int) {
+			defer waitGroup.Done()
+			manager.AddTaskToPool("slowProcessing", iteration)
+		}(i)
+	}
+	waitGroup.Wait()
+	if stopped != 1 {
+		t.Errorf("expected the pool to be stopped exactly once, got %d", stopped)
+	}
+}
@@ -0,0 +1,150 @@
+package manager
+
+import (
+	"context"
+	"github.com/ericbrisrubio/go-workers-multipool/pool"
+	"github.com/pkg/errors"
+	"testing"
+	"time"
+)
+
+func TestManager_SetFuncContext(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 2, 2, false)
+	type args struct {
+		poolID     string
+		workerFunc func(context.Context, interface{}) error
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			"Returns error if pool with {poolId} id does not exist",
+			args{"fastProcessing", func(context.Context, interface{}) error { return nil }},
+			true,
+		},
+		{
+			"Returns error if the worker function is nil",
+			args{"slowProcessing", nil},
+			true,
+		},
+		{
+			"Sets function for an existing pool",
+			args{"slowProcessing", func(context.Context, interface{}) error { return nil }},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := manager.SetFuncContext(tt.args.poolID, tt.args.workerFunc); (err != nil) != tt.wantErr {
+				t.Errorf("SetFuncContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManager_AddTaskToPoolContextCancelled(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 2, 2, false)
+	manager.pools["slowProcessing"] = &pool.GoWorkerPoolFake{}
+	executed := 0
+	manager.SetFuncContext("slowProcessing", func(ctx context.Context, data interface{}) error {
+		executed++
+		return nil
+	})
+	manager.StartPool("slowProcessing")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := manager.AddTaskToPoolContext(ctx, "slowProcessing", "cancelled task"); err != nil {
+		t.Fatalf("AddTaskToPoolContext() error = %v", err)
+	}
+	manager.AddTaskToPoolContext(context.Background(), "slowProcessing", "task")
+	stats, _ := manager.PoolStats("slowProcessing")
+	if executed != 1 || stats.CancelledTasks != 1 || stats.CompletedTasks != 1 {
+		t.Errorf("expected the cancelled task to be skipped, executed %d, stats %+v", executed, stats)
+	}
+}
+
+func TestManager_KillPoolCancelsTasks(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 1, 10, false)
+	started := make(chan struct{}, 1)
+	manager.SetFuncContext("slowProcessing", func(ctx context.Context, data interface{}) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	manager.StartPool("slowProcessing")
+	for i := 0; i < 3; i++ {
+		manager.AddTaskToPool("slowProcessing", i)
+	}
+	<-started
+
+	if err := manager.KillPool("slowProcessing"); err != nil {
+		t.Fatalf("KillPool() error = %v", err)
+	}
+	stats, _ := manager.PoolStats("slowProcessing")
+	if stats.State != PoolStopped || stats.CancelledTasks != 3 {
+		t.Errorf("expected a stopped pool with 3 cancelled tasks, got %+v", stats)
+	}
+	if err := manager.KillPool("slowProcessing"); err == nil {
+		t.Error("KillPool() on a stopped pool must fail")
+	}
+
+	if err := manager.RestartPool("slowProcessing"); err != nil {
+		t.Fatalf("RestartPool() error = %v", err)
+	}
+	manager.SetFuncContext("slowProcessing", func(ctx context.Context, data interface{}) error {
+		return ctx.Err()
+	})
+	manager.AddTaskToPool("slowProcessing", "after restart")
+	manager.StopPool("slowProcessing")
+	if stats, _ = manager.PoolStats("slowProcessing"); stats.CompletedTasks != 1 {
+		t.Errorf("expected the restarted pool to run tasks with a live context, got %+v", stats)
+	}
+}
+
+func TestManager_WaitForPoolContext(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 1, 10, false)
+	manager.SetFunc("slowProcessing", func(interface{}) bool { return true })
+	manager.StartPool("slowProcessing")
+	defer manager.StopPool("slowProcessing")
+
+	if err := manager.WaitForPoolContext(context.Background(), "fastProcessing"); err == nil {
+		t.Error("WaitForPoolContext() must fail for a pool not defined")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	if err := manager.WaitForPoolContext(ctx, "slowProcessing"); errors.Cause(err) != context.DeadlineExceeded {
+		t.Errorf("WaitForPoolContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestManager_WaitForAllPoolsContext(t *testing.T) {
+	manager := createManagerMock(2)
+	if err := manager.WaitForAllPoolsContext(context.Background()); err == nil {
+		t.Error("WaitForAllPoolsContext() must fail if no pool is declared")
+	}
+	manager.AddPool("slowProcessing", 1, 10, false)
+	manager.AddPool("fastProcessing", 0, 10, false)
+	manager.SetFunc("slowProcessing", func(interface{}) bool { return true })
+	manager.StartPool("slowProcessing")
+	manager.StartPool("fastProcessing")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	if err := manager.WaitForAllPoolsContext(ctx); errors.Cause(err) != context.DeadlineExceeded {
+		t.Errorf("WaitForAllPoolsContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	manager.StopPool("slowProcessing")
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := manager.WaitForAllPoolsContext(ctx); err != nil {
+		t.Errorf("WaitForAllPoolsContext() after stopping error = %v", err)
+	}
+}
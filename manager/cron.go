@@ -0,0 +1,177 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Schedule gives the times a recurring job runs at
+type Schedule interface {
+	//Next returns the first time the job runs after t
+	Next(t time.Time) time.Time
+}
+
+//intervalSchedule runs a job every fixed interval
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+func (schedule intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(schedule.interval)
+}
+
+//Every returns a schedule running a job every interval
+func Every(interval time.Duration) (Schedule, error) {
+	if interval <= 0 {
+		return nil, errors.New("interval has to be greater than 0")
+	}
+	return intervalSchedule{interval: interval}, nil
+}
+
+//cronSchedule runs a job at the times matching a cron expression, each field is a bit set of the values it matches
+type cronSchedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+	//anyDay tells whether the day of month or the day of week is `*`, in which case both have to match
+	anyDay bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+//ParseCron parses a standard 5 fields cron expression (minute, hour, day of month, month and day of week)
+//supporting `*`, lists, ranges and steps, the @yearly, @monthly, @weekly, @daily and @hourly macros
+//and `@every <duration>` for intervals
+func ParseCron(expression string) (Schedule, error) {
+	expression = strings.TrimSpace(expression)
+	if strings.HasPrefix(expression, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expression, "@every ")))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid @every interval")
+		}
+		return Every(interval)
+	}
+	if macro, ok := cronMacros[expression]; ok {
+		expression = macro
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil, errors.New(fmt.Sprintf("cron expression `%s` has to have %d fields", expression, len(cronFields)))
+	}
+	sets := make([]uint64, len(fields))
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	//7 is also sunday
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+	return &cronSchedule{
+		minute:     sets[0],
+		hour:       sets[1],
+		dayOfMonth: sets[2],
+		month:      sets[3],
+		dayOfWeek:  sets[4],
+		anyDay:     fields[2] == "*" || fields[4] == "*",
+	}, nil
+}
+
+//parseCronField returns the bit set of the values matched by a comma separated list of ranges
+func parseCronField(expression string, field cronField) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(expression, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			value, err := strconv.Atoi(part[i+1:])
+			if err != nil || value < 1 {
+				return 0, errors.New(fmt.Sprintf("invalid step in %s `%s`", field.name, part))
+			}
+			step = value
+			part = part[:i]
+		}
+		low, high := field.min, field.max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, errors.New(fmt.Sprintf("invalid %s `%s`", field.name, part))
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, errors.New(fmt.Sprintf("invalid %s `%s`", field.name, part))
+				}
+			} else if step > 1 {
+				high = field.max
+			}
+		}
+		if low < field.min || high > field.max || low > high {
+			return 0, errors.New(fmt.Sprintf("%s `%s` out of range %d-%d", field.name, part, field.min, field.max))
+		}
+		for value := low; value <= high; value += step {
+			set |= 1 << uint(value)
+		}
+	}
+	return set, nil
+}
+
+func (schedule *cronSchedule) matchesDay(t time.Time) bool {
+	dayOfMonth := schedule.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := schedule.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if schedule.anyDay {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+//Next returns the first minute after t matching the expression, the zero time if none matches within 5 years
+func (schedule *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if schedule.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !schedule.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if schedule.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if schedule.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
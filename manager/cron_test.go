@@ -0,0 +1,47 @@
+package manager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	from := time.Date(2024, time.March, 15, 10, 30, 20, 0, time.UTC)
+	tests := []struct {
+		name       string
+		expression string
+		want       time.Time
+		wantErr    bool
+	}{
+		{"Every minute", "* * * * *", time.Date(2024, time.March, 15, 10, 31, 0, 0, time.UTC), false},
+		{"Steps", "*/15 * * * *", time.Date(2024, time.March, 15, 10, 45, 0, 0, time.UTC), false},
+		{"Lists and ranges", "0 8-9,22 * * *", time.Date(2024, time.March, 15, 22, 0, 0, 0, time.UTC), false},
+		{"Daily at 02:00", "0 2 * * *", time.Date(2024, time.March, 16, 2, 0, 0, 0, time.UTC), false},
+		{"Day of week", "0 0 * * 1", time.Date(2024, time.March, 18, 0, 0, 0, 0, time.UTC), false},
+		{"Sunday as 7", "0 0 * * 7", time.Date(2024, time.March, 17, 0, 0, 0, 0, time.UTC), false},
+		{"Day of month or day of week", "0 0 1 * 1", time.Date(2024, time.March, 18, 0, 0, 0, 0, time.UTC), false},
+		{"Month rolling the year", "0 0 1 1 *", time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), false},
+		{"Leap day", "0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC), false},
+		{"Macro", "@hourly", time.Date(2024, time.March, 15, 11, 0, 0, 0, time.UTC), false},
+		{"Interval", "@every 90s", from.Add(time.Second * 90), false},
+		{"Returns error for a wrong amount of fields", "* * * *", time.Time{}, true},
+		{"Returns error for a value out of range", "60 * * * *", time.Time{}, true},
+		{"Returns error for an inverted range", "* 10-2 * * *", time.Time{}, true},
+		{"Returns error for a wrong step", "*/0 * * * *", time.Time{}, true},
+		{"Returns error for a wrong interval", "@every soon", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expression)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCron() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
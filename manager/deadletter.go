@@ -0,0 +1,224 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+//FailureReason tells why a task failed terminally
+type FailureReason int
+
+const (
+	//FailureError is the reason of a task whose worker function returned an error or false
+	FailureError FailureReason = iota
+	//FailureRejected is the reason of a task the pool did not accept when it was retried, once its scheduled time came
+	//or when it was handed to the pool after the previous task of its key
+	FailureRejected
+	//FailurePanic is the reason of a task whose worker function panicked
+	FailurePanic
+	//FailureTimeout is the reason of a task whose worker function did not return within its timeout
+	FailureTimeout
+	//FailureUndecodable is the reason of a task replayed from a durable queue whose payload could not be decoded
+	FailureUndecodable
+)
+
+//String returns the name of the reason
+func (reason FailureReason) String() string {
+	switch reason {
+	case FailureError:
+		return "error"
+	case FailureRejected:
+		return "rejected"
+	case FailurePanic:
+		return "panic"
+	case FailureTimeout:
+		return "timeout"
+	case FailureUndecodable:
+		return "undecodable"
+	}
+	return fmt.Sprintf("FailureReason(%d)", int(reason))
+}
+
+//DeadLetter is a task that failed terminally, kept by a dead-letter queue so it can be inspected and re-driven
+type DeadLetter struct {
+	//ID identifies the dead letter within the manager
+	ID uint64
+	//PoolID is the pool the task failed in
+	PoolID string
+	Data   interface{}
+	Err    error
+	Reason FailureReason
+	//Attempts is the amount of times the task was run
+	Attempts    int
+	SubmittedAt time.Time
+	FailedAt    time.Time
+}
+
+//deadLetterStore keeps the dead-letter queues of a manager by name
+type deadLetterStore struct {
+	mutex  sync.Mutex
+	lastID uint64
+	queues map[string][]DeadLetter
+}
+
+//push appends letter to queue assigning its id
+func (store *deadLetterStore) push(queue string, letter DeadLetter) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.queues == nil {
+		store.queues = make(map[string][]DeadLetter)
+	}
+	store.lastID++
+	letter.ID = store.lastID
+	store.queues[queue] = append(store.queues[queue], letter)
+}
+
+//find returns the position of the letter with id in queue, it has to be called holding the mutex
+func (store *deadLetterStore) find(queue string, id uint64) (int, error) {
+	for i, letter := range store.queues[queue] {
+		if letter.ID == id {
+			return i, nil
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("dead letter %d does not exist in `%s` queue", id, queue))
+}
+
+//take removes the letter with id from queue and returns it
+func (store *deadLetterStore) take(queue string, id uint64) (DeadLetter, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	i, err := store.find(queue, id)
+	if err != nil {
+		return DeadLetter{}, err
+	}
+	letter := store.queues[queue][i]
+	store.queues[queue] = append(store.queues[queue][:i], store.queues[queue][i+1:]...)
+	return letter, nil
+}
+
+//restore puts back in queue a letter taken from it, keeping the queue sorted by id
+func (store *deadLetterStore) restore(queue string, letter DeadLetter) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	letters := store.queues[queue]
+	i := sort.Search(len(letters), func(i int) bool { return letters[i].ID > letter.ID })
+	letters = append(letters, DeadLetter{})
+	copy(letters[i+1:], letters[i:])
+	letters[i] = letter
+	if store.queues == nil {
+		store.queues = make(map[string][]DeadLetter)
+	}
+	store.queues[queue] = letters
+}
+
+//deadLetterStore returns the dead-letter queues of the manager
+func (manager *Manager) deadLetterStore() *deadLetterStore {
+	manager.deadLettersOnce.Do(func() {
+		manager.deadLetters = &deadLetterStore{}
+	})
+	return manager.deadLetters
+}
+
+//SetDeadLetterQueue makes poolID move its terminally failed tasks to the dead-letter queue named queue,
+//an empty name stops keeping them. Pools sharing a queue name share the queue
+func (manager *Manager) SetDeadLetterQueue(poolID string, queue string) error {
+	_, record, ok := manager.lookup(poolID)
+	if !ok {
+		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
+	}
+	record.setDeadLetterQueue(manager.deadLetterStore(), queue)
+	return nil
+}
+
+//DeadLetterQueues returns the names of the dead-letter queues holding at least a task, sorted alphabetically
+func (manager *Manager) DeadLetterQueues() []string {
+	store := manager.deadLetterStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	queues := make([]string, 0, len(store.queues))
+	for queue, letters := range store.queues {
+		if len(letters) > 0 {
+			queues = append(queues, queue)
+		}
+	}
+	sort.Strings(queues)
+	return queues
+}
+
+//ListDeadLetters returns the tasks held by queue, oldest first
+func (manager *Manager) ListDeadLetters(queue string) []DeadLetter {
+	store := manager.deadLetterStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return append([]DeadLetter{}, store.queues[queue]...)
+}
+
+//InspectDeadLetter returns the task with id held by queue
+func (manager *Manager) InspectDeadLetter(queue string, id uint64) (DeadLetter, error) {
+	store := manager.deadLetterStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	i, err := store.find(queue, id)
+	if err != nil {
+		return DeadLetter{}, err
+	}
+	return store.queues[queue][i], nil
+}
+
+//RedriveDeadLetter enqueues again the task with id held by queue to poolID, or to the pool it failed in if poolID is empty.
+//The task leaves the queue once it has been enqueued and is run as a new task
+func (manager *Manager) RedriveDeadLetter(queue string, id uint64, poolID string) error {
+	store := manager.deadLetterStore()
+	letter, err := store.take(queue, id)
+	if err != nil {
+		return err
+	}
+	if poolID == "" {
+		poolID = letter.PoolID
+	}
+	if err := manager.enqueue(poolID, &task{ctx: context.Background(), data: letter.Data}); err != nil {
+		store.restore(queue, letter)
+		return err
+	}
+	return nil
+}
+
+//PurgeDeadLetters drops every task held by queue and returns how many they were
+func (manager *Manager) PurgeDeadLetters(queue string) int {
+	store := manager.deadLetterStore()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	purged := len(store.queues[queue])
+	delete(store.queues, queue)
+	return purged
+}
+
+func (record *poolRecord) setDeadLetterQueue(store *deadLetterStore, queue string) {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.deadLetters = store
+	record.deadLetterQueue = queue
+}
+
+//bury moves task to the dead-letter queue of the pool, if it has one
+func (record *poolRecord) bury(task *task, err error, reason FailureReason) {
+	record.mutex.Lock()
+	store, queue := record.deadLetters, record.deadLetterQueue
+	record.mutex.Unlock()
+	if store == nil || queue == "" {
+		return
+	}
+	store.push(queue, DeadLetter{
+		PoolID:      record.poolID,
+		Data:        task.data,
+		Err:         err,
+		Reason:      reason,
+		Attempts:    task.attempt,
+		SubmittedAt: task.submittedAt,
+		FailedAt:    time.Now(),
+	})
+}
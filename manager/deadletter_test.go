@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestManager_DeadLetters(t *testing.T) {
+	manager := createManagerMock(2)
+	errUnavailable := errors.New("service unavailable")
+	manager.AddPoolWithOptions("slowProcessing", PoolOptions{
+		InitialWorkers:  1,
+		MaxJobsInQueue:  10,
+		Retry:           &RetryPolicy{MaxAttempts: 2},
+		DeadLetterQueue: "failures",
+	})
+	manager.AddPool("fastProcessing", 1, 10, false)
+	manager.SetDeadLetterQueue("fastProcessing", "failures")
+	var healthy int32
+	workerFunc := func(ctx context.Context, data interface{}) error {
+		if atomic.LoadInt32(&healthy) == 0 {
+			return errUnavailable
+		}
+		return nil
+	}
+	manager.SetFuncContext("slowProcessing", workerFunc)
+	manager.SetFuncContext("fastProcessing", workerFunc)
+	manager.StartPool("slowProcessing")
+	manager.StartPool("fastProcessing")
+	manager.AddTaskToPool("slowProcessing", "slow task")
+	manager.AddTaskToPool("fastProcessing", "fast task")
+	manager.StopPool("slowProcessing")
+	manager.StopPool("fastProcessing")
+
+	if got := manager.DeadLetterQueues(); !reflect.DeepEqual(got, []string{"failures"}) {
+		t.Errorf("DeadLetterQueues() = %v", got)
+	}
+	letters := manager.ListDeadLetters("failures")
+	if len(letters) != 2 {
+		t.Fatalf("ListDeadLetters() = %+v, want 2 letters", letters)
+	}
+	attempts := map[string]int{"slowProcessing": 2, "fastProcessing": 1}
+	for _, letter := range letters {
+		if letter.Err != errUnavailable || letter.Reason != FailureError || letter.Attempts != attempts[letter.PoolID] ||
+			letter.SubmittedAt.IsZero() || letter.FailedAt.Before(letter.SubmittedAt) {
+			t.Errorf("unexpected dead letter %+v", letter)
+		}
+	}
+	if got, err := manager.InspectDeadLetter("failures", letters[0].ID); err != nil || got.Data != letters[0].Data {
+		t.Errorf("InspectDeadLetter() = %+v, %v", got, err)
+	}
+	if _, err := manager.InspectDeadLetter("failures", 100); err == nil {
+		t.Error("InspectDeadLetter() must fail for a letter not held by the queue")
+	}
+
+	atomic.StoreInt32(&healthy, 1)
+	if err := manager.RedriveDeadLetter("failures", letters[0].ID, ""); err == nil {
+		t.Error("RedriveDeadLetter() to a stopped pool must fail")
+	}
+	if got := manager.ListDeadLetters("failures"); len(got) != 2 || got[0].ID != letters[0].ID {
+		t.Errorf("a letter that could not be re-driven must stay in the queue, got %+v", got)
+	}
+	manager.RestartPool("slowProcessing")
+	manager.RestartPool("fastProcessing")
+	if err := manager.RedriveDeadLetter("failures", letters[0].ID, ""); err != nil {
+		t.Errorf("RedriveDeadLetter() error = %v", err)
+	}
+	if err := manager.RedriveDeadLetter("failures", letters[1].ID, "slowProcessing"); err != nil {
+		t.Errorf("RedriveDeadLetter() to another pool error = %v", err)
+	}
+	manager.StopPool("slowProcessing")
+	manager.StopPool("fastProcessing")
+	slowStats, _ := manager.PoolStats("slowProcessing")
+	fastStats, _ := manager.PoolStats("fastProcessing")
+	if slowStats.CompletedTasks+fastStats.CompletedTasks != 2 || slowStats.CompletedTasks < 1 {
+		t.Errorf("expected the re-driven tasks to be processed, got %+v and %+v", slowStats, fastStats)
+	}
+	if got := manager.ListDeadLetters("failures"); len(got) != 0 {
+		t.Errorf("ListDeadLetters() after re-driving = %+v", got)
+	}
+}
+
+func TestManager_PurgeDeadLetters(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPoolWithOptions("slowProcessing", PoolOptions{InitialWorkers: 1, MaxJobsInQueue: 10, DeadLetterQueue: "slowProcessing"})
+	manager.SetFunc("slowProcessing", func(interface{}) bool { return false })
+	manager.StartPool("slowProcessing")
+	for i := 0; i < 3; i++ {
+		manager.AddTaskToPool("slowProcessing", i)
+	}
+	manager.StopPool("slowProcessing")
+
+	if purged := manager.PurgeDeadLetters("slowProcessing"); purged != 3 {
+		t.Errorf("PurgeDeadLetters() = %d, want 3", purged)
+	}
+	if got := manager.ListDeadLetters("slowProcessing"); len(got) != 0 {
+		t.Errorf("ListDeadLetters() after purging = %+v", got)
+	}
+	if got := manager.DeadLetterQueues(); len(got) != 0 {
+		t.Errorf("DeadLetterQueues() after purging = %v", got)
+	}
+}
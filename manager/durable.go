@@ -0,0 +1,148 @@
+package manager
+
+import (
+	"context"
+	"github.com/ericbrisrubio/go-workers-multipool/durable"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+//DurableQueue keeps the tasks submitted to a pool in a log on local disk until they are over, so the ones
+//not processed when the process stops are enqueued again by the next StartPool. Delivery is at-least-once:
+//a task is acked once the worker function succeeds or its failure is final, and replayed otherwise
+type DurableQueue struct {
+	//Path is the file of the log, it cannot be shared by pools
+	Path string
+	//Codec turns the data of the tasks into the payloads of the log and back
+	Codec durable.Codec
+}
+
+func (queue *DurableQueue) validate() error {
+	if queue.Path == "" {
+		return errors.New("durable queue Path cannot be empty")
+	}
+	if queue.Codec == nil {
+		return errors.New("durable queue Codec cannot be nil")
+	}
+	return nil
+}
+
+//durableQueue is the durable queue of a pool. live holds the entries of the log enqueued in the pool by this
+//process, the other entries not acked were left by a previous one
+type durableQueue struct {
+	mutex sync.Mutex
+	log   *durable.Log
+	codec durable.Codec
+	live  map[uint64]bool
+}
+
+func openDurableQueue(options *DurableQueue) (*durableQueue, error) {
+	log, err := durable.Open(options.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &durableQueue{log: log, codec: options.Codec, live: make(map[uint64]bool)}, nil
+}
+
+//persist appends data to the log and returns the id of its entry
+func (queue *durableQueue) persist(data interface{}) (uint64, error) {
+	payload, err := queue.codec.Encode(data)
+	if err != nil {
+		return 0, errors.Wrap(err, "encoding task for the durable queue")
+	}
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	id, err := queue.log.Append(payload)
+	if err != nil {
+		return 0, err
+	}
+	queue.live[id] = true
+	return id, nil
+}
+
+//claim returns the entries not acked that are not enqueued in the pool, they are considered enqueued from now on
+func (queue *durableQueue) claim() []durable.Entry {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	entries := []durable.Entry{}
+	for _, entry := range queue.log.Pending() {
+		if !queue.live[entry.ID] {
+			queue.live[entry.ID] = true
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+//settle marks the entry id as not enqueued in the pool anymore, removing it from the log if ack is set
+func (queue *durableQueue) settle(id uint64, ack bool) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	delete(queue.live, id)
+	if ack {
+		// an entry that could not be acked is replayed, as delivery is at-least-once
+		queue.log.Ack(id)
+	}
+}
+
+//settleDurable settles the entry of task in the durable queue of the pool (if any) once task is over,
+//the cancelled tasks are kept to be replayed
+func (record *poolRecord) settleDurable(task *task, outcome taskOutcome) {
+	if record.durable != nil && task.durableID != 0 {
+		record.durable.settle(task.durableID, outcome != outcomeCancelled)
+	}
+}
+
+//conclude releases what task holds once it is over: its entry in the durable queue and its key
+func (record *poolRecord) conclude(pool taskAdder, task *task, outcome taskOutcome) {
+	record.settleDurable(task, outcome)
+	record.advanceKey(pool, task)
+}
+
+//replay enqueues again the tasks a previous run left in the durable queue of poolID, waiting for room in the pool.
+//If the pool has no workers to make room, the tasks that do not fit are enqueued in the background instead.
+//Tasks whose payload cannot be decoded are moved to the dead-letter queue of the pool, if it has one
+func (manager *Manager) replay(poolID string) {
+	_, record, ok := manager.lookup(poolID)
+	if !ok || record.durable == nil {
+		return
+	}
+	manager.replayEntries(poolID, record, record.durable.claim(), false)
+}
+
+//replayEntries enqueues entries in poolID waiting for room in its queue. Unless it runs in the background,
+//it hands the entries left to a background replay once the queue is full and the pool has no workers.
+//The entries the pool does not accept anymore stay in the log to be replayed by its next start
+func (manager *Manager) replayEntries(poolID string, record *poolRecord, entries []durable.Entry, background bool) {
+	for i, entry := range entries {
+		data, err := record.durable.codec.Decode(entry.Payload)
+		if err != nil {
+			record.bury(&task{data: entry.Payload, submittedAt: time.Now()}, err, FailureUndecodable)
+			record.durable.settle(entry.ID, true)
+			continue
+		}
+		for {
+			room, err := manager.tryEnqueue(poolID, &task{ctx: context.Background(), data: data, durableID: entry.ID})
+			if err == nil {
+				break
+			}
+			if err != ErrQueueFull {
+				for _, left := range entries[i:] {
+					record.durable.settle(left.ID, false)
+				}
+				return
+			}
+			if !background && manager.workerBudget().get(poolID) == 0 {
+				go manager.replayEntries(poolID, record, entries[i:], true)
+				return
+			}
+			<-room
+		}
+	}
+}
+
+//close closes the log, the entries not acked are replayed once the pool is added again with the same Path
+func (queue *durableQueue) close() {
+	queue.log.Close()
+}
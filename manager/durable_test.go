@@ -0,0 +1,176 @@
+package manager
+
+import (
+	"context"
+	"github.com/ericbrisrubio/go-workers-multipool/durable"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestManager_AddDurablePool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.log")
+	tests := []struct {
+		name    string
+		queue   *DurableQueue
+		wantErr bool
+	}{
+		{"Returns error if the path is empty", &DurableQueue{Codec: durable.BytesCodec{}}, true},
+		{"Returns error if the codec is nil", &DurableQueue{Path: path}, true},
+		{"Returns error if the log cannot be opened", &DurableQueue{Path: filepath.Join(path, "missing", "orders.log"), Codec: durable.BytesCodec{}}, true},
+		{"Adds the pool", &DurableQueue{Path: path, Codec: durable.BytesCodec{}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := createManagerMock(1)
+			err := manager.AddPoolWithOptions("orders", PoolOptions{MaxJobsInQueue: 10, Durable: tt.queue})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AddPoolWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			manager.RemovePool("orders")
+		})
+	}
+}
+
+//processedOrders collects the data processed by a pool
+type processedOrders struct {
+	mutex sync.Mutex
+	data  []interface{}
+}
+
+func (orders *processedOrders) process(data interface{}) bool {
+	orders.mutex.Lock()
+	defer orders.mutex.Unlock()
+	orders.data = append(orders.data, data)
+	return data != "poison"
+}
+
+func TestManager_DurableReplay(t *testing.T) {
+	options := PoolOptions{InitialWorkers: 1, MaxJobsInQueue: 2, Durable: &DurableQueue{Path: filepath.Join(t.TempDir(), "orders.log"), Codec: durable.JSONCodec[string]{}}}
+	previous := createManagerMock(1)
+	previous.AddPoolWithOptions("orders", options)
+	previous.SetFunc("orders", func(interface{}) bool { return true })
+	for _, order := range []string{"first", "second"} {
+		if err := previous.AddTaskToPool("orders", order); err != nil {
+			t.Fatalf("AddTaskToPool() error = %v", err)
+		}
+	}
+	if stats, _ := previous.PoolStats("orders"); stats.PersistedTasks != 2 {
+		t.Errorf("PersistedTasks = %d, want 2", stats.PersistedTasks)
+	}
+	// the process stops before the pool is started
+	previous.RemovePool("orders")
+
+	manager := createManagerMock(1)
+	manager.AddPoolWithOptions("orders", options)
+	processed := &processedOrders{}
+	manager.SetFunc("orders", processed.process)
+	manager.AddTaskToPool("orders", "poison")
+	if err := manager.StartPool("orders"); err != nil {
+		t.Fatalf("StartPool() error = %v", err)
+	}
+	manager.StopPool("orders")
+	if want := []interface{}{"poison", "first", "second"}; !reflect.DeepEqual(processed.data, want) {
+		t.Errorf("processed %v, want %v", processed.data, want)
+	}
+	if stats, _ := manager.PoolStats("orders"); stats.PersistedTasks != 0 {
+		t.Errorf("PersistedTasks = %d, the tasks over must be acked", stats.PersistedTasks)
+	}
+	manager.RestartPool("orders")
+	manager.StopPool("orders")
+	if len(processed.data) != 3 {
+		t.Errorf("processed %v, the tasks acked must not be replayed", processed.data)
+	}
+}
+
+func TestManager_DurableCancelled(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPoolWithOptions("orders", PoolOptions{InitialWorkers: 1, MaxJobsInQueue: 10, Durable: &DurableQueue{Path: filepath.Join(t.TempDir(), "orders.log"), Codec: durable.BytesCodec{}}})
+	started := make(chan struct{})
+	processed := &processedOrders{}
+	manager.SetFuncContext("orders", func(ctx context.Context, data interface{}) error {
+		if string(data.([]byte)) == "slow" {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		processed.process(string(data.([]byte)))
+		return nil
+	})
+	manager.StartPool("orders")
+	manager.AddTaskToPool("orders", []byte("slow"))
+	<-started
+	manager.KillPool("orders")
+	if stats, _ := manager.PoolStats("orders"); stats.CancelledTasks != 1 || stats.PersistedTasks != 1 {
+		t.Errorf("PoolStats() once killed = %+v, the cancelled task must be kept", stats)
+	}
+
+	manager.SetFunc("orders", processed.process)
+	manager.RestartPool("orders")
+	manager.StopPool("orders")
+	if !reflect.DeepEqual(processed.data, []interface{}{[]byte("slow")}) {
+		t.Errorf("processed %v once restarted, want the cancelled task", processed.data)
+	}
+}
+
+func TestManager_DurableUndecodable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.log")
+	log, _ := durable.Open(path)
+	log.Append([]byte("not json"))
+	log.Close()
+
+	manager := createManagerMock(1)
+	manager.AddPoolWithOptions("orders", PoolOptions{InitialWorkers: 1, MaxJobsInQueue: 10, DeadLetterQueue: "orders-dlq", Durable: &DurableQueue{Path: path, Codec: durable.JSONCodec[string]{}}})
+	manager.SetFunc("orders", func(interface{}) bool { return true })
+	manager.StartPool("orders")
+	manager.StopPool("orders")
+	letters := manager.ListDeadLetters("orders-dlq")
+	if len(letters) != 1 || letters[0].Reason != FailureUndecodable || string(letters[0].Data.([]byte)) != "not json" {
+		t.Errorf("ListDeadLetters() = %+v, want the undecodable payload", letters)
+	}
+	if stats, _ := manager.PoolStats("orders"); stats.PersistedTasks != 0 {
+		t.Errorf("PersistedTasks = %d, the undecodable payload must be acked", stats.PersistedTasks)
+	}
+}
+
+func TestManager_DurableReplayWithoutWorkers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.log")
+	previous := createManagerMock(1)
+	previous.AddPoolWithOptions("orders", PoolOptions{MaxJobsInQueue: 10, Durable: &DurableQueue{Path: path, Codec: durable.JSONCodec[string]{}}})
+	previous.SetFunc("orders", func(interface{}) bool { return true })
+	for _, order := range []string{"first", "second", "third", "fourth", "fifth"} {
+		previous.AddTaskToPool("orders", order)
+	}
+	previous.RemovePool("orders")
+
+	manager := createManagerMock(1)
+	manager.AddPoolWithOptions("orders", PoolOptions{MaxJobsInQueue: 2, Durable: &DurableQueue{Path: path, Codec: durable.JSONCodec[string]{}}})
+	processed := &processedOrders{}
+	manager.SetFunc("orders", processed.process)
+	started := make(chan error, 1)
+	go func() {
+		started <- manager.StartPool("orders")
+	}()
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Fatalf("StartPool() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("StartPool() of a pool without workers is waiting for room to replay its log")
+	}
+	if stats, _ := manager.PoolStats("orders"); stats.PersistedTasks != 5 {
+		t.Errorf("PersistedTasks = %d, want 5", stats.PersistedTasks)
+	}
+
+	manager.EditPoolWorkersAmount("orders", 1)
+	if !waitFor(func() bool {
+		processed.mutex.Lock()
+		defer processed.mutex.Unlock()
+		return len(processed.data) == 5
+	}) {
+		t.Errorf("processed %v once the pool has a worker, want the 5 orders", processed.data)
+	}
+}
@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+//Future is a handle to the outcome of a task submitted with SubmitWithResult
+type Future struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+//Result is the outcome of a task, as sent over the results channel of a pool
+type Result struct {
+	PoolID string
+	Data   interface{}
+	Value  interface{}
+	Err    error
+}
+
+func newFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+//resolve sets the outcome of the task, it has to be called only once
+func (future *Future) resolve(value interface{}, err error) {
+	future.value = value
+	future.err = err
+	close(future.done)
+}
+
+//Done returns a channel closed once the task has been processed
+func (future *Future) Done() <-chan struct{} {
+	return future.done
+}
+
+//Await blocks until the task has been processed, returning the value and the error of the worker function,
+//or until ctx is done, returning the error of ctx.
+//Tasks cancelled before being picked up by a worker return the error of their context
+func (future *Future) Await(ctx context.Context) (interface{}, error) {
+	select {
+	case <-future.done:
+		return future.value, future.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+//SetResultFunc defines a function whose returned value is the result of each task executed by an specific pool
+func (manager *Manager) SetResultFunc(poolID string, workerFunc func(context.Context, interface{}) (interface{}, error)) error {
+	if workerFunc == nil {
+		return errors.New("workerFunc cannot be nil")
+	}
+	return register(manager, poolID, workerFunc)
+}
+
+//SubmitWithResult enqueues a new task to be accomplished by the desired pool and returns a future to await its result.
+//The value of a task run by a function defined through SetFunc is the returned bool
+func (manager *Manager) SubmitWithResult(poolID string, data interface{}) (*Future, error) {
+	if data == nil {
+		return nil, errors.New("data cannot be nil")
+	}
+	return manager.untyped(poolID).SubmitWithResult(data)
+}
+
+//SetResultsChannel makes poolID send the result of every processed task over results, nil stops sending them.
+//Workers block while results is full, so it has to be consumed as long as the pool is running
+func (manager *Manager) SetResultsChannel(poolID string, results chan<- Result) error {
+	_, record, ok := manager.lookup(poolID)
+	if !ok {
+		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
+	}
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.results = results
+	return nil
+}
+
+//deliver resolves the future of task and sends its result over the results channel of the pool (if any).
+//The send is given up once the pool is killed
+func (record *poolRecord) deliver(task *task, value interface{}, err error) {
+	if task.future != nil {
+		task.future.resolve(value, err)
+	}
+	record.mutex.Lock()
+	results := record.results
+	record.mutex.Unlock()
+	if results == nil {
+		return
+	}
+	select {
+	case results <- Result{PoolID: record.poolID, Data: task.data, Value: value, Err: err}:
+	case <-task.poolCtx.Done():
+	}
+}
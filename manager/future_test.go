@@ -0,0 +1,145 @@
+package manager
+
+import (
+	"context"
+	"github.com/ericbrisrubio/go-workers-multipool/pool"
+	"github.com/pkg/errors"
+	"testing"
+	"time"
+)
+
+func TestManager_SubmitWithResult(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 2, 10, false)
+	manager.SetResultFunc("slowProcessing", func(ctx context.Context, data interface{}) (interface{}, error) {
+		size := data.(int)
+		if size < 0 {
+			return nil, errors.New("negative size")
+		}
+		return size * 2, nil
+	})
+	manager.StartPool("slowProcessing")
+	defer manager.StopPool("slowProcessing")
+
+	tests := []struct {
+		name      string
+		poolID    string
+		data      interface{}
+		wantErr   bool
+		wantValue interface{}
+		wantAwait bool
+	}{
+		{"Returns error if pool with {poolId} id does not exist", "fastProcessing", 1, true, nil, false},
+		{"Returns error if data is nil", "slowProcessing", nil, true, nil, false},
+		{"Returns the value of the worker function", "slowProcessing", 21, false, 42, false},
+		{"Returns the error of the worker function", "slowProcessing", -1, false, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			future, err := manager.SubmitWithResult(tt.poolID, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SubmitWithResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			value, err := future.Await(ctx)
+			if (err != nil) != tt.wantAwait || value != tt.wantValue {
+				t.Errorf("Await() = %v, %v, want %v, error %v", value, err, tt.wantValue, tt.wantAwait)
+			}
+		})
+	}
+}
+
+func TestManager_SubmitWithResultBoolFunc(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 2, 2, false)
+	manager.pools["slowProcessing"] = &pool.GoWorkerPoolFake{}
+	manager.SetFunc("slowProcessing", func(data interface{}) bool {
+		return data.(string) != ""
+	})
+	manager.StartPool("slowProcessing")
+
+	future, _ := manager.SubmitWithResult("slowProcessing", "image")
+	if value, err := future.Await(context.Background()); value != true || err != nil {
+		t.Errorf("Await() = %v, %v, want true, nil", value, err)
+	}
+	future, _ = manager.SubmitWithResult("slowProcessing", "")
+	if value, err := future.Await(context.Background()); value != false || err != ErrTaskFailed {
+		t.Errorf("Await() = %v, %v, want false, %v", value, err, ErrTaskFailed)
+	}
+}
+
+func TestFuture_AwaitContext(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 1, 2, false)
+	manager.SetFunc("slowProcessing", func(data interface{}) bool { return true })
+
+	future, err := manager.SubmitWithResult("slowProcessing", "never started")
+	if err != nil {
+		t.Fatalf("SubmitWithResult() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	if _, err := future.Await(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Await() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	select {
+	case <-future.Done():
+		t.Error("Done() must not be closed for a task not processed")
+	default:
+	}
+}
+
+func TestTypedPool_SubmitWithResult(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 2, 2, false)
+	manager.pools["slowProcessing"] = &pool.GoWorkerPoolFake{}
+	typedPool, _ := Register(manager, "slowProcessing", func(ctx context.Context, task imageTask) error {
+		if task.size == 0 {
+			return errors.New("empty image")
+		}
+		return nil
+	})
+	manager.StartPool("slowProcessing")
+	future, _ := typedPool.SubmitWithResult(imageTask{path: "/tmp/empty.png"})
+	if _, err := future.Await(context.Background()); err == nil {
+		t.Error("Await() must return the error of the worker function")
+	}
+}
+
+func TestManager_SetResultsChannel(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 2, 10, false)
+	if err := manager.SetResultsChannel("fastProcessing", make(chan Result)); err == nil {
+		t.Error("SetResultsChannel() must fail for a pool not defined")
+	}
+	results := make(chan Result, 3)
+	if err := manager.SetResultsChannel("slowProcessing", results); err != nil {
+		t.Fatalf("SetResultsChannel() error = %v", err)
+	}
+	manager.SetFunc("slowProcessing", func(data interface{}) bool {
+		return data.(int)%2 == 0
+	})
+	manager.StartPool("slowProcessing")
+	for i := 0; i < 3; i++ {
+		manager.AddTaskToPool("slowProcessing", i)
+	}
+	manager.StopPool("slowProcessing")
+	close(results)
+
+	failed := 0
+	for result := range results {
+		if result.PoolID != "slowProcessing" {
+			t.Errorf("unexpected result %+v", result)
+		}
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("expected 1 failed result, got %d", failed)
+	}
+}
@@ -0,0 +1,299 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//OverlapPolicy tells what a recurring job does when it is due while its previous run is still queued or running
+type OverlapPolicy int
+
+const (
+	//OverlapConcurrent submits the run anyway, so runs can overlap
+	OverlapConcurrent OverlapPolicy = iota
+	//OverlapSkip skips the run
+	OverlapSkip
+	//OverlapQueue submits the run once the previous one is over
+	OverlapQueue
+)
+
+//String returns the name of the policy
+func (policy OverlapPolicy) String() string {
+	switch policy {
+	case OverlapConcurrent:
+		return "concurrent"
+	case OverlapSkip:
+		return "skip"
+	case OverlapQueue:
+		return "queue"
+	}
+	return fmt.Sprintf("OverlapPolicy(%d)", int(policy))
+}
+
+//RecurringJob submits Payload to the pool PoolID every time its Schedule is due
+type RecurringJob struct {
+	//Name identifies the job within the manager
+	Name     string
+	PoolID   string
+	Schedule Schedule
+	Payload  interface{}
+	Overlap  OverlapPolicy
+}
+
+//JobInfo is a snapshot of a recurring job
+type JobInfo struct {
+	Name    string
+	PoolID  string
+	Overlap OverlapPolicy
+	Paused  bool
+	//Next is when the job is due next, the zero time while it is paused
+	Next time.Time
+	//LastRun is when a run of the job was last submitted
+	LastRun time.Time
+	//Runs is the amount of runs submitted
+	Runs int
+	//Skipped is the amount of runs skipped because of the overlap policy or because the pool did not accept them
+	Skipped int
+}
+
+//recurringJob runs a RecurringJob in its own goroutine
+type recurringJob struct {
+	RecurringJob
+	mutex   sync.Mutex
+	paused  bool
+	next    time.Time
+	lastRun time.Time
+	runs    int
+	skipped int
+	control chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	submit  func(poolID string, data interface{}) (*Future, error)
+}
+
+//run waits for each due time of the job and submits its runs following its overlap policy
+func (job *recurringJob) run() {
+	defer close(job.done)
+	var previous *Future
+	backlog := 0
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		var due <-chan time.Time
+		if next := job.schedule(); !next.IsZero() {
+			timer.Reset(time.Until(next))
+			due = timer.C
+		}
+		var previousDone <-chan struct{}
+		if previous != nil {
+			previousDone = previous.Done()
+		}
+		select {
+		case <-due:
+			job.advance()
+			if previous != nil && !isDone(previous) {
+				switch job.Overlap {
+				case OverlapSkip:
+					job.count(false)
+					continue
+				case OverlapQueue:
+					backlog++
+					continue
+				}
+			}
+			previous = job.fire()
+		case <-previousDone:
+			previous = nil
+			if backlog > 0 {
+				backlog--
+				previous = job.fire()
+			}
+		case <-job.control:
+		case <-job.stop:
+			return
+		}
+	}
+}
+
+func isDone(future *Future) bool {
+	select {
+	case <-future.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+//schedule returns the next due time of the job computing it if needed, the zero time while paused
+func (job *recurringJob) schedule() time.Time {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	if job.paused {
+		job.next = time.Time{}
+		return job.next
+	}
+	if job.next.IsZero() {
+		job.next = job.Schedule.Next(time.Now())
+	}
+	return job.next
+}
+
+//advance moves the job past its current due time
+func (job *recurringJob) advance() {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	job.next = job.Schedule.Next(job.next)
+	if now := time.Now(); !job.next.IsZero() && job.next.Before(now) {
+		job.next = job.Schedule.Next(now)
+	}
+}
+
+//fire submits a run of the job, returning nil if the pool did not accept it
+func (job *recurringJob) fire() *Future {
+	future, err := job.submit(job.PoolID, job.Payload)
+	job.count(err == nil)
+	return future
+}
+
+func (job *recurringJob) count(submitted bool) {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	if !submitted {
+		job.skipped++
+		return
+	}
+	job.runs++
+	job.lastRun = time.Now()
+}
+
+func (job *recurringJob) setPaused(paused bool) {
+	job.mutex.Lock()
+	job.paused = paused
+	job.next = time.Time{}
+	job.mutex.Unlock()
+	select {
+	case job.control <- struct{}{}:
+	case <-job.done:
+	}
+}
+
+func (job *recurringJob) info() JobInfo {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	return JobInfo{
+		Name:    job.Name,
+		PoolID:  job.PoolID,
+		Overlap: job.Overlap,
+		Paused:  job.paused,
+		Next:    job.next,
+		LastRun: job.lastRun,
+		Runs:    job.runs,
+		Skipped: job.skipped,
+	}
+}
+
+func (job *recurringJob) close() {
+	close(job.stop)
+	<-job.done
+}
+
+//AddRecurringJob registers job and starts submitting its runs
+func (manager *Manager) AddRecurringJob(job RecurringJob) error {
+	if job.Name == "" || strings.Trim(job.Name, " ") == "" {
+		return errors.New("job Name cannot be empty")
+	}
+	if job.Schedule == nil {
+		return errors.New("job Schedule cannot be nil")
+	}
+	if job.Payload == nil {
+		return errors.New("job Payload cannot be nil")
+	}
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	if manager.shutdown {
+		return ErrShutdown
+	}
+	if _, ok := manager.pools[job.PoolID]; !ok {
+		return errors.New(fmt.Sprintf("No pool exists for poolID: %s", job.PoolID))
+	}
+	if _, ok := manager.jobs[job.Name]; ok {
+		return errors.New(fmt.Sprintf("A job named `%s` already exist", job.Name))
+	}
+	if manager.jobs == nil {
+		manager.jobs = make(map[string]*recurringJob)
+	}
+	recurring := &recurringJob{
+		RecurringJob: job,
+		control:      make(chan struct{}),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+		submit:       manager.SubmitWithResult,
+	}
+	manager.jobs[job.Name] = recurring
+	go recurring.run()
+	return nil
+}
+
+//ListJobs returns the recurring jobs sorted by name
+func (manager *Manager) ListJobs() []JobInfo {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	jobs := make([]JobInfo, 0, len(manager.jobs))
+	for _, job := range manager.jobs {
+		jobs = append(jobs, job.info())
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Name < jobs[j].Name
+	})
+	return jobs
+}
+
+//PauseJob stops submitting the runs of the job named name until it is resumed
+func (manager *Manager) PauseJob(name string) error {
+	job, ok := manager.getJob(name)
+	if !ok {
+		return errors.New(fmt.Sprintf("job `%s` does not exist", name))
+	}
+	job.setPaused(true)
+	return nil
+}
+
+//ResumeJob submits again the runs of the job named name, starting from its next due time
+func (manager *Manager) ResumeJob(name string) error {
+	job, ok := manager.getJob(name)
+	if !ok {
+		return errors.New(fmt.Sprintf("job `%s` does not exist", name))
+	}
+	job.setPaused(false)
+	return nil
+}
+
+//RemoveJob stops and unregisters the job named name, its runs already submitted are not affected
+func (manager *Manager) RemoveJob(name string) error {
+	manager.mutex.Lock()
+	job, ok := manager.jobs[name]
+	delete(manager.jobs, name)
+	manager.mutex.Unlock()
+	if !ok {
+		return errors.New(fmt.Sprintf("job `%s` does not exist", name))
+	}
+	job.close()
+	return nil
+}
+
+func (manager *Manager) getJob(name string) (*recurringJob, bool) {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	job, ok := manager.jobs[name]
+	return job, ok
+}
@@ -0,0 +1,154 @@
+package manager
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//waitFor polls condition until it holds or a second went by
+func waitFor(condition func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return true
+}
+
+func TestManager_AddRecurringJob(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("maintenance", 1, 10, false)
+	every, _ := Every(time.Minute)
+	tests := []struct {
+		name    string
+		job     RecurringJob
+		wantErr bool
+	}{
+		{"Returns error if the name is empty", RecurringJob{PoolID: "maintenance", Schedule: every, Payload: "vacuum"}, true},
+		{"Returns error if the schedule is nil", RecurringJob{Name: "vacuum", PoolID: "maintenance", Payload: "vacuum"}, true},
+		{"Returns error if the pool does not exist", RecurringJob{Name: "vacuum", PoolID: "cleanup", Schedule: every, Payload: "vacuum"}, true},
+		{"Registers the job", RecurringJob{Name: "vacuum", PoolID: "maintenance", Schedule: every, Payload: "vacuum"}, false},
+		{"Returns error if the name is taken", RecurringJob{Name: "vacuum", PoolID: "maintenance", Schedule: every, Payload: "vacuum"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := manager.AddRecurringJob(tt.job); (err != nil) != tt.wantErr {
+				t.Errorf("AddRecurringJob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	manager.RemoveJob("vacuum")
+}
+
+func TestManager_RecurringJobLifecycle(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("maintenance", 1, 10, false)
+	var processed int64
+	manager.SetFunc("maintenance", func(interface{}) bool {
+		atomic.AddInt64(&processed, 1)
+		return true
+	})
+	manager.StartPool("maintenance")
+	defer manager.StopPool("maintenance")
+	every, _ := Every(time.Millisecond * 5)
+	manager.AddRecurringJob(RecurringJob{Name: "vacuum", PoolID: "maintenance", Schedule: every, Payload: "vacuum"})
+
+	if !waitFor(func() bool { return atomic.LoadInt64(&processed) >= 3 }) {
+		t.Fatalf("the job was run %d times", atomic.LoadInt64(&processed))
+	}
+	jobs := manager.ListJobs()
+	if len(jobs) != 1 || jobs[0].Name != "vacuum" || jobs[0].Runs < 3 || jobs[0].LastRun.IsZero() {
+		t.Errorf("ListJobs() = %+v", jobs)
+	}
+
+	manager.PauseJob("vacuum")
+	if jobs = manager.ListJobs(); !jobs[0].Paused || !jobs[0].Next.IsZero() {
+		t.Errorf("ListJobs() once paused = %+v", jobs)
+	}
+	waitFor(func() bool { return manager.ListJobs()[0].Runs == int(atomic.LoadInt64(&processed)) })
+	paused := atomic.LoadInt64(&processed)
+	time.Sleep(time.Millisecond * 20)
+	if atomic.LoadInt64(&processed) != paused {
+		t.Error("a paused job must not be run")
+	}
+	manager.ResumeJob("vacuum")
+	if !waitFor(func() bool { return atomic.LoadInt64(&processed) > paused }) {
+		t.Error("a resumed job must be run again")
+	}
+
+	if err := manager.RemoveJob("vacuum"); err != nil {
+		t.Errorf("RemoveJob() error = %v", err)
+	}
+	if err := manager.PauseJob("vacuum"); err == nil {
+		t.Error("PauseJob() must fail for a removed job")
+	}
+	if jobs = manager.ListJobs(); len(jobs) != 0 {
+		t.Errorf("ListJobs() after RemoveJob() = %+v", jobs)
+	}
+}
+
+func TestManager_RecurringJobOverlap(t *testing.T) {
+	tests := []struct {
+		name        string
+		overlap     OverlapPolicy
+		wantSkipped bool
+		wantMax     int64
+	}{
+		{"Skips the runs due while the previous one is running", OverlapSkip, true, 1},
+		{"Queues the runs due while the previous one is running", OverlapQueue, false, 1},
+		{"Runs concurrently", OverlapConcurrent, false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := createManagerMock(1)
+			manager.AddPool("maintenance", 2, 10, false)
+			var running, maxRunning, processed int64
+			started := make(chan struct{}, 1)
+			release := make(chan struct{})
+			manager.SetFunc("maintenance", func(interface{}) bool {
+				select {
+				case started <- struct{}{}:
+				default:
+				}
+				current := atomic.AddInt64(&running, 1)
+				for {
+					max := atomic.LoadInt64(&maxRunning)
+					if current <= max || atomic.CompareAndSwapInt64(&maxRunning, max, current) {
+						break
+					}
+				}
+				<-release
+				atomic.AddInt64(&running, -1)
+				atomic.AddInt64(&processed, 1)
+				return true
+			})
+			manager.StartPool("maintenance")
+			every, _ := Every(time.Millisecond * 5)
+			manager.AddRecurringJob(RecurringJob{Name: "vacuum", PoolID: "maintenance", Schedule: every, Payload: "vacuum", Overlap: tt.overlap})
+			<-started
+			// the first run is held until a run is due while it is running, and overlaps it if the policy allows so
+			first := manager.ListJobs()[0].Next
+			if !waitFor(func() bool {
+				return manager.ListJobs()[0].Next.After(first) && atomic.LoadInt64(&maxRunning) == tt.wantMax
+			}) {
+				t.Errorf("no run was due while the first one was running, %d overlapping", atomic.LoadInt64(&maxRunning))
+			}
+			close(release)
+			if !waitFor(func() bool { return atomic.LoadInt64(&processed) >= 2 }) {
+				t.Errorf("the job was run %d times", atomic.LoadInt64(&processed))
+			}
+			skipped := manager.ListJobs()[0].Skipped
+			manager.RemoveJob("vacuum")
+			manager.StopPool("maintenance")
+			if (skipped > 0) != tt.wantSkipped {
+				t.Errorf("runs skipped = %d, want skipped %v", skipped, tt.wantSkipped)
+			}
+			if got := atomic.LoadInt64(&maxRunning); got != tt.wantMax {
+				t.Errorf("runs overlapping = %d, want %d", got, tt.wantMax)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package manager
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"sort"
+	"sync"
+)
+
+//hotKeysAmount is the amount of keys reported in the stats of a pool
+const hotKeysAmount = 5
+
+//KeyStats tells how many tasks of a key are pending in a pool
+type KeyStats struct {
+	Key string
+	//Pending is the amount of tasks of the key queued or in flight
+	Pending int
+}
+
+//keyedQueue keeps, for every key with a task in flight, the tasks of the key waiting for it in submission order
+type keyedQueue struct {
+	mutex sync.Mutex
+	keys  map[string][]*task
+}
+
+func newKeyedQueue() *keyedQueue {
+	return &keyedQueue{keys: make(map[string][]*task)}
+}
+
+//claim returns true if the key of submitted has no task in flight, in which case submitted is the one in flight from now on.
+//Otherwise submitted waits behind the other tasks of its key
+func (queue *keyedQueue) claim(submitted *task) bool {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	if waiting, busy := queue.keys[submitted.key]; busy {
+		queue.keys[submitted.key] = append(waiting, submitted)
+		return false
+	}
+	queue.keys[submitted.key] = []*task{}
+	return true
+}
+
+//next returns the task of key that goes in flight once the previous one is over, nil if none is waiting
+func (queue *keyedQueue) next(key string) *task {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	waiting := queue.keys[key]
+	if len(waiting) == 0 {
+		delete(queue.keys, key)
+		return nil
+	}
+	queue.keys[key] = waiting[1:]
+	return waiting[0]
+}
+
+//stats returns the amount of keys with pending tasks and the ones with most of them
+func (queue *keyedQueue) stats() (int, []KeyStats) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	if len(queue.keys) == 0 {
+		return 0, nil
+	}
+	keys := make([]KeyStats, 0, len(queue.keys))
+	for key, waiting := range queue.keys {
+		keys = append(keys, KeyStats{Key: key, Pending: len(waiting) + 1})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Pending != keys[j].Pending {
+			return keys[i].Pending > keys[j].Pending
+		}
+		return keys[i].Key < keys[j].Key
+	})
+	if len(keys) > hotKeysAmount {
+		keys = keys[:hotKeysAmount]
+	}
+	return len(queue.keys), keys
+}
+
+//SubmitKeyed enqueues a new task to be accomplished by the desired pool after every task previously submitted with
+//the same key: the tasks of a key run one at a time and in submission order, while tasks of different keys run in parallel.
+//Keyed tasks are never spilled over to other pools
+func (manager *Manager) SubmitKeyed(poolID string, key string, data interface{}) error {
+	if data == nil {
+		return errors.New("data cannot be nil")
+	}
+	if key == "" {
+		return errors.New("key cannot be empty")
+	}
+	return manager.enqueue(poolID, &task{ctx: context.Background(), data: data, key: key})
+}
+
+//advanceKey hands to pool the task waiting behind task for the same key, once task is over.
+//The waiting tasks the pool does not accept fail and the following one is tried
+func (record *poolRecord) advanceKey(pool taskAdder, task *task) {
+	if task.key == "" {
+		return
+	}
+	for next := record.keys.next(task.key); next != nil; next = record.keys.next(task.key) {
+		err := record.dispatch(pool, next)
+		if err == nil {
+			return
+		}
+		next.discard()
+		record.bury(next, err, FailureRejected)
+		record.abandon(next, err, outcomeFailed)
+		record.settleDurable(next, outcomeFailed)
+	}
+}
@@ -0,0 +1,109 @@
+package manager
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestManager_SubmitKeyed(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("orders", 1, 10, false)
+	manager.SetFunc("orders", func(interface{}) bool { return true })
+	type args struct {
+		poolID string
+		key    string
+		data   interface{}
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"Returns error if the pool does not exist", args{"invoices", "customer-1", "order"}, true},
+		{"Returns error if the key is empty", args{"orders", "", "order"}, true},
+		{"Returns error if the data is nil", args{"orders", "customer-1", nil}, true},
+		{"Submits the task", args{"orders", "customer-1", "order"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := manager.SubmitKeyed(tt.args.poolID, tt.args.key, tt.args.data); (err != nil) != tt.wantErr {
+				t.Errorf("SubmitKeyed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManager_SubmitKeyedOrder(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("orders", 4, 100, false)
+	type order struct {
+		customer string
+		number   int
+	}
+	var mutex sync.Mutex
+	processed := map[string][]int{}
+	inFlight := map[string]int{}
+	running, maxRunning, overlaps := 0, 0, 0
+	manager.SetFunc("orders", func(data interface{}) bool {
+		order := data.(order)
+		mutex.Lock()
+		if inFlight[order.customer]++; inFlight[order.customer] > 1 {
+			overlaps++
+		}
+		if running++; running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+		time.Sleep(time.Millisecond)
+		mutex.Lock()
+		inFlight[order.customer]--
+		running--
+		processed[order.customer] = append(processed[order.customer], order.number)
+		mutex.Unlock()
+		// failed tasks must not hold the following ones of their key
+		return order.number%5 != 0
+	})
+	manager.StartPool("orders")
+	for number := 0; number < 10; number++ {
+		for customer := 0; customer < 4; customer++ {
+			customer := fmt.Sprintf("customer-%d", customer)
+			if err := manager.SubmitKeyed("orders", customer, order{customer, number}); err != nil {
+				t.Fatalf("SubmitKeyed() error = %v", err)
+			}
+		}
+	}
+	manager.StopPool("orders")
+
+	want := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	for customer, numbers := range processed {
+		if !reflect.DeepEqual(numbers, want) {
+			t.Errorf("orders of %s processed in order %v", customer, numbers)
+		}
+	}
+	if len(processed) != 4 || overlaps != 0 {
+		t.Errorf("%d customers processed with %d overlapping orders", len(processed), overlaps)
+	}
+	if maxRunning < 2 {
+		t.Errorf("orders of different customers must run in parallel, at most %d ran at once", maxRunning)
+	}
+	if stats, _ := manager.PoolStats("orders"); stats.ActiveKeys != 0 || stats.HotKeys != nil || stats.FailedTasks != 8 {
+		t.Errorf("PoolStats() once stopped = %+v", stats)
+	}
+}
+
+func TestManager_HotKeys(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("orders", 1, 10, false)
+	manager.SetFunc("orders", func(interface{}) bool { return true })
+	for _, key := range []string{"customer-1", "customer-2", "customer-1", "customer-3", "customer-1", "customer-2"} {
+		manager.SubmitKeyed("orders", key, "order")
+	}
+	stats, _ := manager.PoolStats("orders")
+	want := []KeyStats{{"customer-1", 3}, {"customer-2", 2}, {"customer-3", 1}}
+	if stats.ActiveKeys != 3 || !reflect.DeepEqual(stats.HotKeys, want) {
+		t.Errorf("ActiveKeys = %d, HotKeys = %v, want %v", stats.ActiveKeys, stats.HotKeys, want)
+	}
+}
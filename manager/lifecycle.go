@@ -0,0 +1,456 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"reflect"
+	"sync"
+	"time"
+)
+
+//ErrNoWorkers is returned when a pool cannot be stopped because it has queued tasks and no workers to process them
+var ErrNoWorkers = errors.New("pool has no workers to process its queued tasks")
+
+//PoolState is the stage of the lifecycle a pool is in
+type PoolState int
+
+const (
+	//PoolDefined is the state of a pool that has been added but not started yet
+	PoolDefined PoolState = iota
+	//PoolStarted is the state of a pool whose workers are processing tasks
+	PoolStarted
+	//PoolPaused is the state of a pool whose workers have been paused
+	PoolPaused
+	//PoolDraining is the state of a pool finishing its queued tasks before stopping
+	PoolDraining
+	//PoolStopped is the state of a pool without workers that does not accept tasks anymore
+	PoolStopped
+	//PoolRemoved is the state of a pool that is no longer registered in the manager
+	PoolRemoved
+)
+
+//String returns the name of the state, as shown in the errors and logs
+func (state PoolState) String() string {
+	switch state {
+	case PoolDefined:
+		return "defined"
+	case PoolStarted:
+		return "started"
+	case PoolPaused:
+		return "paused"
+	case PoolDraining:
+		return "draining"
+	case PoolStopped:
+		return "stopped"
+	case PoolRemoved:
+		return "removed"
+	}
+	return fmt.Sprintf("PoolState(%d)", int(state))
+}
+
+//StateError is returned when an operation is not allowed in the current state of a pool
+type StateError struct {
+	PoolID    string
+	State     PoolState
+	Operation string
+}
+
+//Error returns the operation refused and the state of the pool that refused it
+func (err *StateError) Error() string {
+	return fmt.Sprintf("cannot %s pool `%s` while it is %s", err.Operation, err.PoolID, err.State)
+}
+
+//poolRecord keeps the lifecycle state and the pending tasks of a pool
+type poolRecord struct {
+	mutex sync.Mutex
+	//resizing serializes the resizes done in the background, by the autoscaler, with the termination of the workers
+	//by a stop. It is taken before mutex
+	resizing        sync.Mutex
+	poolID          string
+	state           PoolState
+	pausedFrom      PoolState
+	pending         int
+	queued          map[*task]struct{}
+	idle            chan struct{}
+	room            chan struct{}
+	running         int
+	completed       int
+	failed          int
+	cancelled       int
+	retried         int
+	panicked        int
+	timedOut        int
+	abandoned       int
+	returned        chan struct{}
+	timeout         time.Duration
+	capacity        int
+	maxCapacity     int
+	spillover       *SpilloverPolicy
+	spilled         int
+	rejectedDue     int
+	runTime         time.Duration
+	limiter         *tokenBucket
+	throttled       time.Duration
+	priority        *priorityQueue
+	keys            *keyedQueue
+	durable         *durableQueue
+	autoscaler      *autoscaler
+	ctx             context.Context
+	cancel          context.CancelFunc
+	results         chan<- Result
+	retryPolicy     *RetryPolicy
+	dataType        reflect.Type
+	deadLetters     *deadLetterStore
+	deadLetterQueue string
+}
+
+func newPoolRecord(poolID string) *poolRecord {
+	idle := make(chan struct{})
+	close(idle)
+	record := &poolRecord{poolID: poolID, state: PoolDefined, idle: idle, room: make(chan struct{}), keys: newKeyedQueue()}
+	record.queued = make(map[*task]struct{})
+	record.returned = make(chan struct{})
+	record.ctx, record.cancel = context.WithCancel(context.Background())
+	return record
+}
+
+//check returns a StateError if the pool is not in one of the allowed states, it has to be called holding the mutex
+func (record *poolRecord) check(operation string, allowed ...PoolState) error {
+	for _, state := range allowed {
+		if record.state == state {
+			return nil
+		}
+	}
+	return &StateError{PoolID: record.poolID, State: record.state, Operation: operation}
+}
+
+//checkState returns a StateError if the pool is not in one of the allowed states
+func (record *poolRecord) checkState(operation string, allowed ...PoolState) error {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	return record.check(operation, allowed...)
+}
+
+//getState returns the current state of the pool
+func (record *poolRecord) getState() PoolState {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	return record.state
+}
+
+//setState moves the pool to the given state
+func (record *poolRecord) setState(state PoolState) {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.state = state
+	record.vacate()
+}
+
+//acquire registers a new pending task if the pool still accepts tasks and has room in its queue and returns the context of the current pool run
+func (record *poolRecord) acquire() (context.Context, error) {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	if err := record.check("add tasks to", PoolDefined, PoolStarted, PoolPaused); err != nil {
+		return nil, err
+	}
+	if record.capacity > 0 && record.pending-record.running >= record.capacity {
+		return nil, ErrQueueFull
+	}
+	if record.pending == 0 {
+		record.idle = make(chan struct{})
+	}
+	record.pending++
+	return record.ctx, nil
+}
+
+//release removes a task from the pending ones, it has to be called holding the mutex
+func (record *poolRecord) release() {
+	record.pending--
+	if record.pending == 0 {
+		close(record.idle)
+	}
+}
+
+//reject releases a task the pool could not enqueue
+func (record *poolRecord) reject() {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.release()
+	record.vacate()
+}
+
+//begin marks a pending task as picked up by a worker
+func (record *poolRecord) begin() {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.running++
+	record.vacate()
+}
+
+//finish marks a running task as processed
+func (record *poolRecord) finish(outcome taskOutcome) {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.running--
+	switch outcome {
+	case outcomeCompleted:
+		record.completed++
+	case outcomeCancelled:
+		record.cancelled++
+
+	default:
+		record.failed++
+	}
+	record.release()
+}
+
+//track registers task as handed to the pool and waiting for a worker
+func (record *poolRecord) track(task *task) {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.queued[task] = struct{}{}
+}
+
+//untrack forgets a task the pool did not accept
+func (record *poolRecord) untrack(task *task) {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	delete(record.queued, task)
+}
+
+//pickUp marks task as picked up by a worker, as begin does. It returns false if the task was cancelled while queued
+func (record *poolRecord) pickUp(task *task) bool {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	if _, ok := record.queued[task]; !ok {
+		return false
+	}
+	delete(record.queued, task)
+	record.running++
+	record.vacate()
+	return true
+}
+
+//dropQueued removes from the pending tasks the ones waiting for a worker and returns them,
+//the pool skips them if it still hands them to a worker
+func (record *poolRecord) dropQueued() []*task {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	dropped := make([]*task, 0, len(record.queued))
+	for task := range record.queued {
+		task.discard()
+		dropped = append(dropped, task)
+		record.cancelled++
+		record.release()
+	}
+	record.queued = make(map[*task]struct{})
+	if len(dropped) > 0 {
+		record.vacate()
+	}
+	return dropped
+}
+
+//cancelQueued cancels the tasks waiting for a worker of pool, resolving their futures with err, and returns how many they were.
+//The tasks a cancelled one was holding back by its key are cancelled along
+func (record *poolRecord) cancelQueued(pool taskAdder, err error) int {
+	cancelled := 0
+	for dropped := record.dropQueued(); len(dropped) > 0; dropped = record.dropQueued() {
+		for _, task := range dropped {
+			record.deliver(task, nil, err)
+			record.conclude(pool, task, outcomeCancelled)
+		}
+		cancelled += len(dropped)
+	}
+	return cancelled
+}
+
+//queuedTasks returns the amount of tasks waiting for a worker
+func (record *poolRecord) queuedTasks() int {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	return len(record.queued)
+}
+
+//postpone marks a running task as waiting to be retried, it stays pending until it runs again
+func (record *poolRecord) postpone() {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.running--
+	record.retried++
+}
+
+//idleChan returns a channel closed once the pool has no pending tasks
+func (record *poolRecord) idleChan() <-chan struct{} {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	return record.idle
+}
+
+//StopPool stops accepting tasks for poolID, waits until its queued tasks are processed and terminates its workers.
+//It returns ErrNoWorkers, leaving the pool as it was, if tasks are queued in a pool without workers to process them
+func (manager *Manager) StopPool(poolID string) error {
+	return manager.stopPool(context.Background(), poolID, "stop", false)
+}
+
+//StopPoolContext stops poolID as StopPool does, waiting for its queued tasks until ctx is done.
+//If ctx is done first the pool is put back in the state it was in and the error of ctx is returned
+func (manager *Manager) StopPoolContext(ctx context.Context, poolID string) error {
+	if ctx == nil {
+		return errors.New("ctx cannot be nil")
+	}
+	return manager.stopPool(ctx, poolID, "stop", false)
+}
+
+//KillPool stops poolID cancelling the context of its queued and in-flight tasks: queued tasks are skipped and
+//the pool is stopped as soon as the in-flight ones return. In a pool without workers the queued tasks are cancelled right away
+func (manager *Manager) KillPool(poolID string) error {
+	return manager.stopPool(context.Background(), poolID, "kill", true)
+}
+
+//stopPool moves poolID to draining, waits until it has no pending tasks and terminates its workers.
+//A pool already draining can only be killed, which cancels its tasks and lets the ongoing stop finish.
+//If ctx is done before the pool is drained, or the workers cannot be terminated, the pool is put back
+//in the state it was in and the error is returned
+func (manager *Manager) stopPool(ctx context.Context, poolID string, operation string, cancelTasks bool) error {
+	pool, record, ok := manager.lookup(poolID)
+	if !ok {
+		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
+	}
+	record.mutex.Lock()
+	allowed := []PoolState{PoolStarted, PoolPaused}
+	if cancelTasks {
+		allowed = append(allowed, PoolDraining)
+	}
+	if err := record.check(operation, allowed...); err != nil {
+		record.mutex.Unlock()
+		return err
+	}
+	if cancelTasks {
+		record.cancel()
+	}
+	previousState := record.state
+	record.state = PoolDraining
+	record.mutex.Unlock()
+
+	if previousState == PoolDraining {
+		return nil
+	}
+	revert := func() {
+		record.mutex.Lock()
+		if record.ctx.Err() != nil {
+			record.ctx, record.cancel = context.WithCancel(context.Background())
+		}
+		record.state = previousState
+		record.vacate()
+		record.mutex.Unlock()
+		if previousState == PoolPaused {
+			pool.PauseAllWorkers()
+		}
+	}
+	if previousState == PoolPaused {
+		pool.ResumeAllWorkers()
+	}
+	if manager.workerBudget().get(poolID) == 0 {
+		// no worker is ever going to pick up the queued tasks
+		if cancelTasks {
+			record.cancelQueued(pool, context.Canceled)
+		} else if record.queuedTasks() > 0 {
+			revert()
+			return ErrNoWorkers
+		}
+	}
+	select {
+	case <-record.idleChan():
+	case <-ctx.Done():
+		revert()
+		return ctx.Err()
+	}
+	record.resizing.Lock()
+	defer record.resizing.Unlock()
+	if err := pool.EditWorkersAmount(0); err != nil {
+		revert()
+		return err
+	}
+	manager.workerBudget().request(poolID, 0, true)
+	record.setState(PoolStopped)
+	return nil
+}
+
+//RemovePool unregisters a defined or stopped pool, so its id can be used again.
+//Tasks still queued in a pool that was never started are discarded, durable pools keep them in their log
+func (manager *Manager) RemovePool(poolID string) error {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	pool, ok := manager.pools[poolID]
+	if !ok {
+		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
+	}
+	record := manager.recordFor(poolID)
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	if err := record.check("remove", PoolDefined, PoolStopped); err != nil {
+		return err
+	}
+	record.cancel()
+	record.setAutoscaler(manager, pool, nil)
+	if record.state == PoolDefined {
+		if err := pool.EditWorkersAmount(0); err != nil {
+			return err
+		}
+	}
+	record.state = PoolRemoved
+	record.vacate()
+	delete(manager.pools, poolID)
+	delete(manager.poolsInitializer, poolID)
+	delete(manager.records, poolID)
+	manager.workerBudget().forget(poolID)
+	if record.durable != nil {
+		record.durable.close()
+	}
+	return nil
+}
+
+//RestartPool starts again the initial workers of poolID, stopping the pool first if it is running.
+//For durable pools it returns once the tasks cancelled by the previous run are enqueued again,
+//if the pool has no workers the ones that do not fit in its queue are enqueued in the background
+func (manager *Manager) RestartPool(poolID string) error {
+	if err := manager.restartPool(poolID); err != nil {
+		return err
+	}
+	manager.replay(poolID)
+	return nil
+}
+
+func (manager *Manager) restartPool(poolID string) error {
+	pool, record, ok := manager.lookup(poolID)
+	if !ok {
+		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
+	}
+	if manager.isShutdown() {
+		return ErrShutdown
+	}
+	if state := record.getState(); state == PoolStarted || state == PoolPaused {
+		if err := manager.StopPool(poolID); err != nil {
+			return err
+		}
+	}
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	if err := record.check("restart", PoolStopped); err != nil {
+		return err
+	}
+	budget := manager.workerBudget()
+	if initialWorkers, _ := budget.request(poolID, manager.initialWorkers(poolID), true); initialWorkers > 0 {
+		// workers of the previous run may still be exiting, so they are added instead of setting a total
+		if err := pool.AddWorkers(initialWorkers); err != nil {
+			budget.request(poolID, 0, true)
+			return err
+		}
+	}
+	if record.ctx.Err() != nil {
+		record.ctx, record.cancel = context.WithCancel(context.Background())
+	}
+	record.state = PoolStarted
+	return nil
+}
@@ -0,0 +1,358 @@
+package manager
+
+import (
+	"context"
+	"github.com/ericbrisrubio/go-workers-multipool/pool"
+	"github.com/pkg/errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestManager_StopPool(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     func(manager *Manager)
+		poolID    string
+		wantErr   bool
+		wantState PoolState
+	}{
+		{
+			"Returns error if pool with {poolId} id is not defined",
+			func(manager *Manager) {},
+			"slowProcessing",
+			true,
+			PoolRemoved,
+		},
+		{
+			"Returns error if the pool has not been started",
+			func(manager *Manager) {},
+			"fastProcessing",
+			true,
+			PoolDefined,
+		},
+		{
+			"Stops a started pool",
+			func(manager *Manager) { manager.StartPool("fastProcessing") },
+			"fastProcessing",
+			false,
+			PoolStopped,
+		},
+		{
+			"Stops a paused pool",
+			func(manager *Manager) {
+				manager.StartPool("fastProcessing")
+				manager.PauseWorkersFromPool("fastProcessing")
+			},
+			"fastProcessing",
+			false,
+			PoolStopped,
+		},
+		{
+			"Returns error if the pool is already stopped",
+			func(manager *Manager) {
+				manager.StartPool("fastProcessing")
+				manager.StopPool("fastProcessing")
+			},
+			"fastProcessing",
+			true,
+			PoolStopped,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := createManagerMock(1)
+			manager.AddPool("fastProcessing", 2, 2, false)
+			manager.pools["fastProcessing"] = &pool.GoWorkerPoolFake{}
+			tt.setup(manager)
+			if err := manager.StopPool(tt.poolID); (err != nil) != tt.wantErr {
+				t.Errorf("StopPool() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, record, ok := manager.lookup(tt.poolID); ok && record.getState() != tt.wantState {
+				t.Errorf("StopPool() state = %v, want %v", record.getState(), tt.wantState)
+			}
+		})
+	}
+}
+
+func TestManager_StopPoolDrainsQueuedTasks(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 2, 10, false)
+	var processed int64
+	manager.SetFunc("slowProcessing", func(data interface{}) bool {
+		time.Sleep(time.Millisecond * 10)
+		atomic.AddInt64(&processed, 1)
+		return true
+	})
+	manager.StartPool("slowProcessing")
+	for i := 0; i < 6; i++ {
+		if err := manager.AddTaskToPool("slowProcessing", i); err != nil {
+			t.Fatalf("AddTaskToPool() error = %v", err)
+		}
+	}
+	if err := manager.StopPool("slowProcessing"); err != nil {
+		t.Fatalf("StopPool() error = %v", err)
+	}
+	if atomic.LoadInt64(&processed) != 6 {
+		t.Errorf("expected 6 processed tasks after stopping, got %d", processed)
+	}
+	err := manager.AddTaskToPool("slowProcessing", "late task")
+	stateErr, ok := errors.Cause(err).(*StateError)
+	if !ok || stateErr.State != PoolStopped {
+		t.Errorf("AddTaskToPool() on a stopped pool error = %v, want a StateError", err)
+	}
+}
+
+func TestManager_StopPoolWithoutWorkers(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 0, 10, false)
+	manager.SetFunc("slowProcessing", func(data interface{}) bool { return true })
+	manager.StartPool("slowProcessing")
+	futures := make([]*Future, 0, 3)
+	for i := 0; i < 3; i++ {
+		future, err := manager.SubmitWithResult("slowProcessing", i)
+		if err != nil {
+			t.Fatalf("SubmitWithResult() error = %v", err)
+		}
+		futures = append(futures, future)
+	}
+	if err := manager.StopPool("slowProcessing"); err != ErrNoWorkers {
+		t.Errorf("StopPool() without workers error = %v, want ErrNoWorkers", err)
+	}
+	if stats, _ := manager.PoolStats("slowProcessing"); stats.State != PoolStarted {
+		t.Errorf("state after StopPool() failed = %v, want started", stats.State)
+	}
+	if err := manager.KillPool("slowProcessing"); err != nil {
+		t.Fatalf("KillPool() without workers error = %v", err)
+	}
+	for _, future := range futures {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		if _, err := future.Await(ctx); err != context.Canceled {
+			t.Errorf("Await() of a task killed while queued error = %v, want context.Canceled", err)
+		}
+		cancel()
+	}
+	if stats, _ := manager.PoolStats("slowProcessing"); stats.State != PoolStopped || stats.CancelledTasks != 3 {
+		t.Errorf("stats after KillPool() = %+v, want stopped with 3 cancelled tasks", stats)
+	}
+}
+
+func TestManager_StopPoolContext(t *testing.T) {
+	tests := []struct {
+		name      string
+		pause     bool
+		wantState PoolState
+	}{
+		{"Puts a started pool back once the deadline is reached", false, PoolStarted},
+		{"Puts a paused pool back once the deadline is reached", true, PoolPaused},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := createManagerMock(1)
+			manager.AddPool("slowProcessing", 1, 10, false)
+			release := make(chan struct{})
+			manager.SetFunc("slowProcessing", func(data interface{}) bool {
+				<-release
+				return true
+			})
+			manager.StartPool("slowProcessing")
+			manager.AddTaskToPool("slowProcessing", "image-1")
+			if tt.pause {
+				manager.PauseWorkersFromPool("slowProcessing")
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			if err := manager.StopPoolContext(ctx, "slowProcessing"); err != context.DeadlineExceeded {
+				t.Errorf("StopPoolContext() error = %v, want context.DeadlineExceeded", err)
+			}
+			if stats, _ := manager.PoolStats("slowProcessing"); stats.State != tt.wantState {
+				t.Errorf("state after StopPoolContext() timed out = %v, want %v", stats.State, tt.wantState)
+			}
+			if tt.pause {
+				manager.ResumeWorkersFromPool("slowProcessing")
+			}
+			close(release)
+			if err := manager.StopPool("slowProcessing"); err != nil {
+				t.Errorf("StopPool() once the task is released error = %v", err)
+			}
+		})
+	}
+}
+
+func TestManager_RemovePool(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(manager *Manager)
+		poolID  string
+		wantErr bool
+	}{
+		{
+			"Returns error if pool with {poolId} id is not defined",
+			func(manager *Manager) {},
+			"slowProcessing",
+			true,
+		},
+		{
+			"Removes a pool that has not been started",
+			func(manager *Manager) {},
+			"fastProcessing",
+			false,
+		},
+		{
+			"Returns error if the pool is running",
+			func(manager *Manager) { manager.StartPool("fastProcessing") },
+			"fastProcessing",
+			true,
+		},
+		{
+			"Removes a stopped pool",
+			func(manager *Manager) {
+				manager.StartPool("fastProcessing")
+				manager.StopPool("fastProcessing")
+			},
+			"fastProcessing",
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := createManagerMock(1)
+			manager.AddPool("fastProcessing", 2, 2, false)
+			manager.pools["fastProcessing"] = &pool.GoWorkerPoolFake{}
+			tt.setup(manager)
+			if err := manager.RemovePool(tt.poolID); (err != nil) != tt.wantErr {
+				t.Errorf("RemovePool() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManager_RemovePoolReusesID(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 2, 2, false)
+	manager.pools["slowProcessing"] = &pool.GoWorkerPoolFake{}
+	_, record, _ := manager.lookup("slowProcessing")
+	if err := manager.RemovePool("slowProcessing"); err != nil {
+		t.Fatalf("RemovePool() error = %v", err)
+	}
+	if record.getState() != PoolRemoved {
+		t.Errorf("expected removed state, got %v", record.getState())
+	}
+	if _, ok := manager.poolsInitializer["slowProcessing"]; ok {
+		t.Error("poolsInitializer entry has not been removed")
+	}
+	if err := manager.AddPool("slowProcessing", 2, 2, false); err != nil {
+		t.Errorf("AddPool() with a removed id error = %v", err)
+	}
+}
+
+func TestManager_RestartPool(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 2, 10, false)
+	var processed int64
+	manager.SetFunc("slowProcessing", func(data interface{}) bool {
+		atomic.AddInt64(&processed, 1)
+		return true
+	})
+	if err := manager.RestartPool("slowProcessing"); err == nil {
+		t.Error("RestartPool() on a pool never started must fail")
+	}
+	manager.StartPool("slowProcessing")
+	manager.AddTaskToPool("slowProcessing", "first run")
+	if err := manager.RestartPool("slowProcessing"); err != nil {
+		t.Fatalf("RestartPool() error = %v", err)
+	}
+	if err := manager.AddTaskToPool("slowProcessing", "second run"); err != nil {
+		t.Fatalf("AddTaskToPool() after restart error = %v", err)
+	}
+	if err := manager.StopPool("slowProcessing"); err != nil {
+		t.Fatalf("StopPool() error = %v", err)
+	}
+	if atomic.LoadInt64(&processed) != 2 {
+		t.Errorf("expected 2 processed tasks, got %d", processed)
+	}
+}
+
+func TestManager_LifecycleTransitions(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 2, 2, false)
+	manager.pools["slowProcessing"] = &pool.GoWorkerPoolFake{}
+	_, record, _ := manager.lookup("slowProcessing")
+	steps := []struct {
+		name      string
+		operation func() error
+		wantErr   bool
+		wantState PoolState
+	}{
+		{"start", func() error { return manager.StartPool("slowProcessing") }, false, PoolStarted},
+		{"start twice", func() error { return manager.StartPool("slowProcessing") }, true, PoolStarted},
+		{"pause", func() error { return manager.PauseWorkersFromPool("slowProcessing") }, false, PoolPaused},
+		{"resume", func() error { return manager.ResumeWorkersFromPool("slowProcessing") }, false, PoolStarted},
+		{"stop", func() error { return manager.StopPool("slowProcessing") }, false, PoolStopped},
+		{"edit workers", func() error { return manager.EditPoolWorkersAmount("slowProcessing", 2) }, true, PoolStopped},
+		{"pause stopped", func() error { return manager.PauseWorkersFromPool("slowProcessing") }, true, PoolStopped},
+		{"restart", func() error { return manager.RestartPool("slowProcessing") }, false, PoolStarted},
+	}
+	for _, step := range steps {
+		if err := step.operation(); (err != nil) != step.wantErr {
+			t.Errorf("%s error = %v, wantErr %v", step.name, err, step.wantErr)
+		}
+		if state := record.getState(); state != step.wantState {
+			t.Errorf("%s state = %v, want %v", step.name, state, step.wantState)
+		}
+	}
+}
+
+func TestManager_PauseResumeWithBusyWorker(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 1, 10, false)
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	manager.SetFunc("slowProcessing", func(interface{}) bool {
+		started <- struct{}{}
+		<-release
+		return true
+	})
+	manager.StartPool("slowProcessing")
+	manager.AddTaskToPool("slowProcessing", "first")
+	<-started
+	manager.AddTaskToPool("slowProcessing", "second")
+	manager.AddTaskToPool("slowProcessing", "third")
+	// lets the dispatcher of goworkerpool take the second task and wait for the busy worker
+	time.Sleep(time.Millisecond * 10)
+
+	if err := manager.PauseWorkersFromPool("slowProcessing"); err != nil {
+		t.Fatalf("PauseWorkersFromPool() error = %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.ResumeWorkersFromPool("slowProcessing")
+	}()
+	// the resume waits for the busy worker, the pool is still available meanwhile
+	time.Sleep(time.Millisecond * 10)
+	statsRead := make(chan struct{})
+	go func() {
+		manager.PoolStats("slowProcessing")
+		close(statsRead)
+	}()
+	select {
+	case <-statsRead:
+	case <-time.After(time.Second):
+		t.Fatal("PoolStats() is blocked by the resume of the pool")
+	}
+	close(release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("pausing and resuming a pool with a busy worker error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ResumeWorkersFromPool() is blocked with a busy worker and queued tasks")
+	}
+	if err := manager.StopPool("slowProcessing"); err != nil {
+		t.Errorf("StopPool() once resumed error = %v", err)
+	}
+	if stats, _ := manager.PoolStats("slowProcessing"); stats.CompletedTasks != 3 {
+		t.Errorf("expected the 3 tasks to be processed once resumed, got %+v", stats)
+	}
+}
@@ -1,82 +1,110 @@
 package manager
 
 import (
+	"context"
 	"fmt"
-	"github.com/enriquebris/goworkerpool"
 	"github.com/ericbrisrubio/go-workers-multipool/pool"
 	"github.com/pkg/errors"
-	"strings"
 	"sync"
 )
 
-//Manager takes care of the different existing pools
+//Manager takes care of the different existing pools.
+//It is safe for concurrent use: task submission and pool operations share a read lock
+//while the registration of new pools takes the exclusive one
 type Manager struct {
+	mutex            sync.RWMutex
 	poolsInitializer map[string]int
-	pools map[string]pool.Descriptor
+	pools            map[string]pool.Descriptor
+	records          map[string]*poolRecord
+	shutdown         bool
+	deadLetters      *deadLetterStore
+	deadLettersOnce  sync.Once
+	router           *Router
+	scheduler        *scheduler
+	jobs             map[string]*recurringJob
+	scalingEvents    chan<- ScalingEvent
+	budget           *workerBudget
+	budgetOnce       sync.Once
 }
 
 //AddPool creates a new pool in the map of pools and returns the success of the operation
 func (manager *Manager) AddPool(poolID string, initialWorkers int, maxJobsInQueue int, verbose bool) error {
-	if poolID == "" || strings.Trim(poolID, " ") == "" {
-		return errors.New("PoolId cannot be empty")
-	}
-	if maxJobsInQueue < 1 {
-		return errors.New("maxJobsInQueue has to be greater than 0")
-	}
-	if manager.isPoolDefined(poolID) {
-		return errors.New(fmt.Sprintf("A pool with `%s` id already exist", poolID))
-	}
-	if manager.pools == nil {
-		manager.pools = make(map[string]pool.Descriptor)
-	}
-	if manager.poolsInitializer == nil {
-		manager.poolsInitializer = make(map[string]int)
+	return manager.AddPoolWithOptions(poolID, PoolOptions{
+		InitialWorkers: initialWorkers,
+		MaxJobsInQueue: maxJobsInQueue,
+		Verbose:        verbose,
+	})
+}
+
+//StartPool makes the workers to start taking care of jobs.
+//For durable pools it returns once the tasks left by a previous run are enqueued again,
+//if the pool has no workers the ones that do not fit in its queue are enqueued in the background
+func (manager *Manager) StartPool(poolID string) error {
+	if err := manager.startPool(poolID); err != nil {
+		return err
 	}
-	manager.pools[poolID] = &pool.GoWorkerPoolAdapter{Pool: goworkerpool.NewPool(0, maxJobsInQueue, verbose)}
-	manager.poolsInitializer[poolID] = initialWorkers
+	manager.replay(poolID)
 	return nil
 }
 
-//StartPool makes the workers to start taking care of jobs
-func (manager *Manager) StartPool(poolID string) error {
-	if !manager.isPoolDefined(poolID) {
+func (manager *Manager) startPool(poolID string) error {
+	pool, record, isDefined := manager.lookup(poolID)
+	if !isDefined {
 		return errors.New(fmt.Sprintf("Pool with `%s` id does not exist", poolID))
 	}
-	if value, ok := manager.poolsInitializer[poolID]; ok {
-		pool, _ := manager.pools[poolID]
-		errEditing := pool.EditWorkersAmount(value)
-		if errEditing != nil {
-			return errEditing
-		}
-	} else {
+	manager.mutex.RLock()
+	value, isInitialized := manager.poolsInitializer[poolID]
+	manager.mutex.RUnlock()
+	if !isInitialized {
 		return errors.New(fmt.Sprintf("error initializing pool with id `%s`", poolID))
 	}
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	if err := record.check("start", PoolDefined); err != nil {
+		return err
+	}
+	// the pool starts with the workers the budget grants, possibly fewer than the initial ones
+	budget := manager.workerBudget()
+	workers, _ := budget.request(poolID, value, true)
+	if err := pool.EditWorkersAmount(workers); err != nil {
+		budget.request(poolID, 0, true)
+		return err
+	}
+	record.state = PoolStarted
 	return nil
 }
 
 //SetFunc defines the function to be executed by an specific pool
 func (manager *Manager) SetFunc(poolID string, workerFunc func(interface{}) bool) error {
-	if manager.isPoolDefined(poolID) {
-		pool, _ := manager.pools[poolID]
-		pool.SetWorkerFunc(workerFunc)
-	} else {
-		return errors.New(fmt.Sprintf("Pool with `%s` id does not exist", poolID))
+	return register[interface{}](manager, poolID, boolHandler(workerFunc))
+}
+
+//SetFuncContext defines a function receiving the context of each task to be executed by an specific pool.
+//The context is done when the task is cancelled or the pool is killed
+func (manager *Manager) SetFuncContext(poolID string, workerFunc func(context.Context, interface{}) error) error {
+	if workerFunc == nil {
+		return errors.New("workerFunc cannot be nil")
 	}
-	return nil
+	return register[interface{}](manager, poolID, errorHandler(workerFunc))
 }
 
-//AddTaskToPool enqueues a new task to be accomplished by the desired pool
+//AddTaskToPool enqueues a new task to be accomplished by the desired pool, waiting for room in its queue.
+//Use TrySubmit to fail fast with ErrQueueFull instead
 func (manager *Manager) AddTaskToPool(poolID string, data interface{}) error {
+	return manager.AddTaskToPoolContext(context.Background(), poolID, data)
+}
+
+//AddTaskToPoolContext enqueues a new task to be accomplished by the desired pool, waiting for room in its queue
+//until ctx is done, ErrQueueFull is returned then. Cancelling ctx cancels the task: it is skipped if no worker
+//picked it up yet, otherwise its context is done
+func (manager *Manager) AddTaskToPoolContext(ctx context.Context, poolID string, data interface{}) error {
+	if ctx == nil {
+		return errors.New("ctx cannot be nil")
+	}
 	if data == nil {
 		return errors.New("data cannot be nil")
 	}
-	if manager.isPoolDefined(poolID) {
-		pool, _ := manager.pools[poolID]
-		pool.AddTask(data)
-	} else {
-		return errors.New(fmt.Sprintf("No pool exists for poolID: %s", poolID))
-	}
-	return nil
+	return manager.untyped(poolID).SubmitContext(ctx, data)
 }
 
 //AddWorkersToPool increments the workers amount in {poolID} by {workersAmount} elements
@@ -84,24 +112,41 @@ func (manager *Manager) AddWorkersToPool(poolID string, amount int) error {
 	if amount == 0 {
 		return errors.New("amount cannot be 0")
 	}
-	if !manager.isPoolDefined(poolID) {
+	pool, record, ok := manager.lookup(poolID)
+	if !ok {
 		return errors.New(fmt.Sprintf("No pool exists for poolID: %s", poolID))
 	}
-	pool, _ := manager.pools[poolID]
-	return pool.AddWorkers(amount)
+	if err := record.checkState("edit workers of", PoolDefined, PoolStarted, PoolPaused); err != nil {
+		return err
+	}
+	budget := manager.workerBudget()
+	if _, err := budget.add(poolID, amount, false); err != nil {
+		return err
+	}
+	if err := pool.AddWorkers(amount); err != nil {
+		budget.add(poolID, -amount, true)
+		return err
+	}
+	return nil
 }
 
 //KillWorkersFromPool decrements the workers amount in {poolID} by {workersAmount} elements
 func (manager *Manager) KillWorkersFromPool(poolID string, amount int) error {
-	if !manager.isPoolDefined(poolID) {
+	pool, record, ok := manager.lookup(poolID)
+	if !ok {
 		return errors.New(fmt.Sprintf("No pool id defined for %s id", poolID))
 	}
 	if amount == 0 {
 		return errors.New("Workers amount cannot be 0")
 	}
-
-	pool, _ := manager.pools[poolID]
-	return pool.KillWorkers(amount)
+	if err := record.checkState("edit workers of", PoolDefined, PoolStarted, PoolPaused); err != nil {
+		return err
+	}
+	if err := pool.KillWorkers(amount); err != nil {
+		return err
+	}
+	manager.workerBudget().add(poolID, -amount, true)
+	return nil
 }
 
 //EditPoolWorkersAmount set a fixed amount {workersAmount} of workers for poolID
@@ -109,51 +154,98 @@ func (manager *Manager) EditPoolWorkersAmount(poolID string, amount int) error {
 	if amount < 0 {
 		return errors.New("amount has to be greater or equal to 0")
 	}
-	if !manager.isPoolDefined(poolID) {
+	pool, record, ok := manager.lookup(poolID)
+	if !ok {
 		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
 	}
-	pool, _ := manager.pools[poolID]
-	return pool.EditWorkersAmount(amount)
+	if err := record.checkState("edit workers of", PoolDefined, PoolStarted, PoolPaused); err != nil {
+		return err
+	}
+	budget := manager.workerBudget()
+	previous := budget.get(poolID)
+	if _, err := budget.request(poolID, amount, false); err != nil {
+		return err
+	}
+	if err := pool.EditWorkersAmount(amount); err != nil {
+		budget.request(poolID, previous, true)
+		return err
+	}
+	return nil
 }
 
 //PauseWorkersFromPool pause the work for all the workers from {poolID}
 func (manager *Manager) PauseWorkersFromPool(poolID string) error {
-	if !manager.isPoolDefined(poolID) {
+	pool, record, ok := manager.lookup(poolID)
+	if !ok {
 		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
 	}
-	pool, _ := manager.pools[poolID]
+	// the backend is paused holding only the resizing lock: the workers need the mutex to finish their tasks
+	record.resizing.Lock()
+	defer record.resizing.Unlock()
+	record.mutex.Lock()
+	if err := record.check("pause", PoolDefined, PoolStarted, PoolPaused); err != nil {
+		record.mutex.Unlock()
+		return err
+	}
+	if record.state != PoolPaused {
+		record.pausedFrom = record.state
+		record.state = PoolPaused
+	}
+	record.mutex.Unlock()
 	pool.PauseAllWorkers()
 	return nil
 }
 
 //ResumeWorkersFromPool resume the works for all the workers from {poolID}
 func (manager *Manager) ResumeWorkersFromPool(poolID string) error {
-	if !manager.isPoolDefined(poolID) {
+	pool, record, ok := manager.lookup(poolID)
+	if !ok {
 		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
 	}
-	pool, _ := manager.pools[poolID]
+	// goworkerpool resumes once its dispatcher gets to the pause, which may wait for a busy worker to finish its task
+	record.resizing.Lock()
+	defer record.resizing.Unlock()
+	record.mutex.Lock()
+	if err := record.check("resume", PoolDefined, PoolStarted, PoolPaused); err != nil {
+		record.mutex.Unlock()
+		return err
+	}
+	if record.state == PoolPaused {
+		record.state = record.pausedFrom
+	}
+	record.mutex.Unlock()
 	pool.ResumeAllWorkers()
 	return nil
 }
 
 //WaitForPool blocks while at least a worker from poolID is alive
 func (manager *Manager) WaitForPool(poolID string) error {
-	if !manager.isPoolDefined(poolID) {
+	pool, ok := manager.getPool(poolID)
+	if !ok {
 		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
 	}
-	pool, _ := manager.pools[poolID]
 	pool.Wait()
 	return nil
 }
 
+//WaitForPoolContext blocks while at least a worker from poolID is alive or until ctx is done
+func (manager *Manager) WaitForPoolContext(ctx context.Context, poolID string) error {
+	pool, ok := manager.getPool(poolID)
+	if !ok {
+		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
+	}
+	return pool.WaitContext(ctx)
+}
+
 //WaitForAllPools blocks while at least a worker from all the pools is alive
 func (manager *Manager) WaitForAllPools() error {
-	if len(manager.pools) == 0 {
+	pools := manager.poolsSnapshot()
+	if len(pools) == 0 {
 		return errors.New("No pool has been declared")
 	}
 	waitGroup := new(sync.WaitGroup)
-	waitGroup.Add(len(manager.pools))
-	for _,poolValue := range manager.pools{
+	waitGroup.Add(len(pools))
+	for _, poolValue := range pools {
 		go func(wg *sync.WaitGroup, pool pool.Descriptor) {
 			pool.Wait()
 			wg.Done()
@@ -163,7 +255,79 @@ func (manager *Manager) WaitForAllPools() error {
 	return nil
 }
 
-func (manager *Manager) isPoolDefined(poolID string) bool {
-	_, isElementInMap := manager.pools[poolID]
-	return isElementInMap
+//WaitForAllPoolsContext blocks while at least a worker from all the pools is alive or until ctx is done
+func (manager *Manager) WaitForAllPoolsContext(ctx context.Context) error {
+	pools := manager.poolsSnapshot()
+	if len(pools) == 0 {
+		return errors.New("No pool has been declared")
+	}
+	waitGroup := new(sync.WaitGroup)
+	waitGroup.Add(len(pools))
+	for _, poolValue := range pools {
+		go func(wg *sync.WaitGroup, pool pool.Descriptor) {
+			pool.WaitContext(ctx)
+			wg.Done()
+		}(waitGroup, poolValue)
+	}
+	waitGroup.Wait()
+	return ctx.Err()
+}
+
+//getPool returns the pool registered with poolID, the lookup is done under the read lock
+func (manager *Manager) getPool(poolID string) (pool.Descriptor, bool) {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	pool, isElementInMap := manager.pools[poolID]
+	return pool, isElementInMap
+}
+
+//lookup returns the pool registered with poolID along with its lifecycle record
+func (manager *Manager) lookup(poolID string) (pool.Descriptor, *poolRecord, bool) {
+	manager.mutex.RLock()
+	pool, isElementInMap := manager.pools[poolID]
+	record := manager.records[poolID]
+	manager.mutex.RUnlock()
+	if !isElementInMap {
+		return nil, nil, false
+	}
+	if record == nil {
+		manager.mutex.Lock()
+		defer manager.mutex.Unlock()
+		if pool, isElementInMap = manager.pools[poolID]; !isElementInMap {
+			return nil, nil, false
+		}
+		record = manager.recordFor(poolID)
+	}
+	return pool, record, true
+}
+
+//recordFor returns the lifecycle record of poolID creating it when missing, it has to be called holding the write lock
+func (manager *Manager) recordFor(poolID string) *poolRecord {
+	if manager.records == nil {
+		manager.records = make(map[string]*poolRecord)
+	}
+	record, ok := manager.records[poolID]
+	if !ok {
+		record = newPoolRecord(poolID)
+		manager.records[poolID] = record
+	}
+	return record
+}
+
+//initialWorkers returns the amount of workers poolID is started with
+func (manager *Manager) initialWorkers(poolID string) int {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	return manager.poolsInitializer[poolID]
+}
+
+//poolsSnapshot returns a copy of the registered pools so they can be used without holding the lock
+func (manager *Manager) poolsSnapshot() []pool.Descriptor {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	pools := make([]pool.Descriptor, 0, len(manager.pools))
+	for _, poolValue := range manager.pools {
+		pools = append(pools, poolValue)
+	}
+	return pools
 }
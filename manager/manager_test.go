@@ -238,6 +238,7 @@ func TestManager_SetFuncForPool(t *testing.T) {
 func TestManager_AddTask(t *testing.T) {
 	manager := createManagerMock(1)
 	manager.AddPool("slowProcessing", 2, 2, false)
+	manager.SetFunc("slowProcessing", func(interface{}) bool { return true })
 	type fields struct {
 		pools map[string]pool.Descriptor
 	}
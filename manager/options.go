@@ -0,0 +1,234 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/ericbrisrubio/go-workers-multipool/pool"
+	"github.com/pkg/errors"
+	"strings"
+	"time"
+)
+
+//PoolOptions gathers the settings of a pool created through AddPoolWithOptions
+type PoolOptions struct {
+	//InitialWorkers is the amount of workers started by StartPool
+	InitialWorkers int
+	//MaxJobsInQueue is the maximum amount of tasks waiting to be processed
+	MaxJobsInQueue int
+	//Verbose enables the logs of the underlying pool
+	Verbose bool
+	//Backend is the name of the pool.Backend running the workers of the pool, empty means pool.GoWorkerPoolBackend
+	Backend string
+	//Retry defines how failed tasks are retried, nil means they are not
+	Retry *RetryPolicy
+	//DeadLetterQueue is the name of the dead-letter queue the terminally failed tasks are moved to, empty means they are dropped
+	DeadLetterQueue string
+	//Timeout bounds how long the worker function can run for each task, 0 means no bound.
+	//It can be overridden for a task by submitting it with a context returned by WithTaskTimeout.
+	//A worker function ignoring its context keeps running once timed out, PoolStats.AbandonedHandlers counts them:
+	//while they are more than the workers of the pool, the workers wait for them to return before running new tasks
+	Timeout time.Duration
+	//Spillover makes the tasks overflow to fallback pools while the pool is saturated, nil means they do not
+	Spillover *SpilloverPolicy
+	//Priority makes the pool run its queued tasks by priority, see SubmitWithPriority. Nil means they are run in order
+	Priority *PriorityPolicy
+	//Durable keeps the tasks of the pool on disk until they are over, nil means they are only kept in memory
+	Durable *DurableQueue
+	//RateLimit bounds how many tasks of the pool start per second, nil means they are not bounded
+	RateLimit *RateLimit
+	//Budget is the part of the worker budget of the manager the pool is entitled to, nil means no guaranteed minimum and a weight of 1
+	Budget *BudgetShare
+	//Autoscale makes the manager resize the workers of the pool following its load, nil means they are only resized on demand
+	Autoscale *AutoscalePolicy
+}
+
+//validate returns an error describing the first invalid option
+func (options PoolOptions) validate() error {
+	if options.MaxJobsInQueue < 1 {
+		return errors.New("maxJobsInQueue has to be greater than 0")
+	}
+	if options.InitialWorkers < 0 {
+		return errors.New("initialWorkers has to be greater or equal to 0")
+	}
+	if options.Timeout < 0 {
+		return errors.New("timeout has to be greater or equal to 0")
+	}
+	if _, err := options.backend(); err != nil {
+		return err
+	}
+	if options.Priority != nil {
+		if err := options.Priority.validate(); err != nil {
+			return err
+		}
+	}
+	if options.Retry != nil {
+		if err := options.Retry.validate(); err != nil {
+			return err
+		}
+	}
+	if options.Durable != nil {
+		if err := options.Durable.validate(); err != nil {
+			return err
+		}
+	}
+	if options.RateLimit != nil {
+		if err := options.RateLimit.validate(); err != nil {
+			return err
+		}
+	}
+	if options.Budget != nil {
+		if err := options.Budget.validate(); err != nil {
+			return err
+		}
+	}
+	if options.Autoscale != nil {
+		if err := options.Autoscale.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//backend returns the pool.Backend chosen by the options
+func (options PoolOptions) backend() (pool.Backend, error) {
+	if options.Backend == "" {
+		return pool.LookupBackend(pool.GoWorkerPoolBackend)
+	}
+	return pool.LookupBackend(options.Backend)
+}
+
+//AddPoolWithOptions creates a new pool in the map of pools configured by options and returns the success of the operation
+func (manager *Manager) AddPoolWithOptions(poolID string, options PoolOptions) error {
+	if poolID == "" || strings.Trim(poolID, " ") == "" {
+		return errors.New("PoolId cannot be empty")
+	}
+	if err := options.validate(); err != nil {
+		return err
+	}
+	if options.Spillover != nil {
+		if err := options.Spillover.validate(poolID); err != nil {
+			return err
+		}
+	}
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	if manager.shutdown {
+		return ErrShutdown
+	}
+	if _, ok := manager.pools[poolID]; ok {
+		return errors.New(fmt.Sprintf("A pool with `%s` id already exist", poolID))
+	}
+	var queue *durableQueue
+	if options.Durable != nil {
+		var err error
+		if queue, err = openDurableQueue(options.Durable); err != nil {
+			return err
+		}
+	}
+	if options.Budget != nil {
+		if err := manager.workerBudget().setShare(poolID, *options.Budget); err != nil {
+			if queue != nil {
+				queue.close()
+			}
+			return err
+		}
+	}
+	if manager.pools == nil {
+		manager.pools = make(map[string]pool.Descriptor)
+	}
+	if manager.poolsInitializer == nil {
+		manager.poolsInitializer = make(map[string]int)
+	}
+	backend, _ := options.backend()
+	manager.pools[poolID] = backend(options.MaxJobsInQueue, options.Verbose)
+	manager.poolsInitializer[poolID] = options.InitialWorkers
+	record := manager.recordFor(poolID)
+	record.retryPolicy = options.Retry
+	record.timeout = options.Timeout
+	record.capacity = options.MaxJobsInQueue
+	record.maxCapacity = options.MaxJobsInQueue
+	record.spillover = options.Spillover
+	record.durable = queue
+	if options.Priority != nil {
+		record.priority = newPriorityQueue(options.Priority)
+	}
+	if options.DeadLetterQueue != "" {
+		record.setDeadLetterQueue(manager.deadLetterStore(), options.DeadLetterQueue)
+	}
+	record.mutex.Lock()
+	record.setRateLimit(options.RateLimit)
+	record.setAutoscaler(manager, manager.pools[poolID], options.Autoscale)
+	record.mutex.Unlock()
+	return nil
+}
+
+//SetInitialWorkers changes the amount of workers poolID is started with by StartPool and RestartPool,
+//the workers of a running pool are edited through EditPoolWorkersAmount
+func (manager *Manager) SetInitialWorkers(poolID string, initialWorkers int) error {
+	if initialWorkers < 0 {
+		return errors.New("initialWorkers has to be greater or equal to 0")
+	}
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	if _, ok := manager.pools[poolID]; !ok {
+		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
+	}
+	manager.poolsInitializer[poolID] = initialWorkers
+	return nil
+}
+
+//SetMaxJobsInQueue changes the maximum amount of tasks waiting to be processed by poolID.
+//It cannot go below the amount of tasks queued at the moment, nor over the MaxJobsInQueue the pool was added with
+//as the backend of the pool is sized by it
+func (manager *Manager) SetMaxJobsInQueue(poolID string, maxJobsInQueue int) error {
+	if maxJobsInQueue < 1 {
+		return errors.New("maxJobsInQueue has to be greater than 0")
+	}
+	_, record, ok := manager.lookup(poolID)
+	if !ok {
+		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
+	}
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	if maxJobsInQueue > record.maxCapacity {
+		return errors.New(fmt.Sprintf("maxJobsInQueue of pool `%s` cannot go over %d, the size of its backend", poolID, record.maxCapacity))
+	}
+	if queued := record.pending - record.running; maxJobsInQueue < queued {
+		return errors.New(fmt.Sprintf("maxJobsInQueue of pool `%s` cannot go below the %d tasks queued", poolID, queued))
+	}
+	record.capacity = maxJobsInQueue
+	return nil
+}
+
+//SetRetryPolicy defines how the failed tasks of poolID are retried, nil means they are not.
+//Tasks already waiting to be retried keep the delay of the previous policy
+func (manager *Manager) SetRetryPolicy(poolID string, policy *RetryPolicy) error {
+	_, record, ok := manager.lookup(poolID)
+	if !ok {
+		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
+	}
+	if policy != nil {
+		if err := policy.validate(); err != nil {
+			return err
+		}
+	}
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.retryPolicy = policy
+	return nil
+}
+
+//SetTimeout bounds how long the worker function of poolID can run for each task, 0 means no bound.
+//It applies to the tasks starting from now on
+func (manager *Manager) SetTimeout(poolID string, timeout time.Duration) error {
+	if timeout < 0 {
+		return errors.New("timeout has to be greater or equal to 0")
+	}
+	_, record, ok := manager.lookup(poolID)
+	if !ok {
+		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
+	}
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.timeout = timeout
+	return nil
+}
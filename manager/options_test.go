@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManager_SetMaxJobsInQueue(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("imageProcessing", 1, 3, false)
+	manager.SetFunc("imageProcessing", func(interface{}) bool { return true })
+	manager.AddTaskToPool("imageProcessing", "image-1")
+	manager.AddTaskToPool("imageProcessing", "image-2")
+	tests := []struct {
+		name           string
+		poolID         string
+		maxJobsInQueue int
+		wantErr        bool
+	}{
+		{"Returns error if the pool is not defined", "videoProcessing", 2, true},
+		{"Returns error if the size is not positive", "imageProcessing", 0, true},
+		{"Returns error if the size is over the one of the backend", "imageProcessing", 4, true},
+		{"Returns error if the size is below the queued tasks", "imageProcessing", 1, true},
+		{"Shrinks the queue", "imageProcessing", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := manager.SetMaxJobsInQueue(tt.poolID, tt.maxJobsInQueue); (err != nil) != tt.wantErr {
+				t.Errorf("SetMaxJobsInQueue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if err := manager.TrySubmit("imageProcessing", "image-3"); err != ErrQueueFull {
+		t.Errorf("TrySubmit() on the shrunk queue error = %v, want ErrQueueFull", err)
+	}
+}
+
+func TestManager_SetPoolSettings(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("imageProcessing", 1, 10, false)
+	tests := []struct {
+		name    string
+		set     func() error
+		wantErr bool
+	}{
+		{"Initial workers", func() error { return manager.SetInitialWorkers("imageProcessing", 3) }, false},
+		{"Negative initial workers", func() error { return manager.SetInitialWorkers("imageProcessing", -1) }, true},
+		{"Initial workers of an undefined pool", func() error { return manager.SetInitialWorkers("videoProcessing", 3) }, true},
+		{"Retry policy", func() error { return manager.SetRetryPolicy("imageProcessing", &RetryPolicy{MaxAttempts: 3}) }, false},
+		{"Invalid retry policy", func() error { return manager.SetRetryPolicy("imageProcessing", &RetryPolicy{}) }, true},
+		{"No retry policy", func() error { return manager.SetRetryPolicy("imageProcessing", nil) }, false},
+		{"Timeout", func() error { return manager.SetTimeout("imageProcessing", time.Second) }, false},
+		{"Negative timeout", func() error { return manager.SetTimeout("imageProcessing", -time.Second) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.set(); (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if manager.initialWorkers("imageProcessing") != 3 {
+		t.Errorf("initialWorkers() = %d, want 3", manager.initialWorkers("imageProcessing"))
+	}
+}
@@ -0,0 +1,38 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+//PanicError is the error of a task whose worker function panicked, the panic is recovered so the worker keeps
+//taking tasks and the pool keeps its size
+type PanicError struct {
+	//Value is the value the worker function panicked with
+	Value interface{}
+	//Stack is the stack trace of the goroutine that panicked
+	Stack []byte
+}
+
+//Error returns the value the worker function panicked with, the stack trace is kept in Stack
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("worker function panicked: %v", err.Value)
+}
+
+//countPanic counts a task whose worker function panicked
+func (record *poolRecord) countPanic() {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.panicked++
+}
+
+//run calls handle recovering a panic of the worker function as a PanicError
+func (handle handler) run(ctx context.Context, data interface{}) (value interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			value, err = nil, &PanicError{Value: recovered, Stack: debug.Stack()}
+		}
+	}()
+	return handle(ctx, data)
+}
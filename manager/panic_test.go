@@ -0,0 +1,70 @@
+package manager
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestManager_PanicIsolation(t *testing.T) {
+	manager := createManagerMock(2)
+	manager.AddPoolWithOptions("slowProcessing", PoolOptions{InitialWorkers: 1, MaxJobsInQueue: 10, DeadLetterQueue: "failures"})
+	manager.AddPool("fastProcessing", 1, 10, false)
+	manager.SetFunc("slowProcessing", func(data interface{}) bool {
+		if data == "boom" {
+			panic("corrupted image")
+		}
+		return true
+	})
+	manager.SetFunc("fastProcessing", func(interface{}) bool { return true })
+	manager.StartPool("slowProcessing")
+	manager.StartPool("fastProcessing")
+
+	future, _ := manager.SubmitWithResult("slowProcessing", "boom")
+	manager.AddTaskToPool("slowProcessing", "image")
+	manager.AddTaskToPool("fastProcessing", "image")
+	_, err := future.Await(context.Background())
+	panicErr, ok := err.(*PanicError)
+	if !ok {
+		t.Fatalf("Await() error = %v, want a PanicError", err)
+	}
+	if panicErr.Value != "corrupted image" || !strings.Contains(string(panicErr.Stack), "panic_test.go") {
+		t.Errorf("PanicError = %v with stack %s", panicErr, panicErr.Stack)
+	}
+	manager.StopPool("slowProcessing")
+	manager.StopPool("fastProcessing")
+
+	stats, _ := manager.PoolStats("slowProcessing")
+	if stats.FailedTasks != 1 || stats.PanickedTasks != 1 || stats.CompletedTasks != 1 {
+		t.Errorf("PoolStats() after a panic = %+v", stats)
+	}
+	if stats, _ = manager.PoolStats("fastProcessing"); stats.CompletedTasks != 1 {
+		t.Errorf("a panic must not affect other pools, got %+v", stats)
+	}
+	if letters := manager.ListDeadLetters("failures"); len(letters) != 1 || letters[0].Reason != FailurePanic {
+		t.Errorf("ListDeadLetters() = %+v, want the panicked task", letters)
+	}
+}
+
+func TestManager_PanicKeepsWorkers(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPoolWithOptions("slowProcessing", PoolOptions{InitialWorkers: 2, MaxJobsInQueue: 10, Retry: &RetryPolicy{MaxAttempts: 2}})
+	manager.SetFuncContext("slowProcessing", func(ctx context.Context, data interface{}) error {
+		if Attempt(ctx) == 1 {
+			panic(data)
+		}
+		return nil
+	})
+	manager.StartPool("slowProcessing")
+	for i := 0; i < 5; i++ {
+		manager.AddTaskToPool("slowProcessing", i)
+	}
+	stats, _ := manager.PoolStats("slowProcessing")
+	if stats.TotalWorkers != 2 {
+		t.Errorf("TotalWorkers after panics = %d, want 2", stats.TotalWorkers)
+	}
+	manager.StopPool("slowProcessing")
+	if stats, _ = manager.PoolStats("slowProcessing"); stats.CompletedTasks != 5 || stats.RetriedTasks != 5 || stats.PanickedTasks != 5 || stats.FailedTasks != 0 {
+		t.Errorf("expected the panicked tasks to be retried, got %+v", stats)
+	}
+}
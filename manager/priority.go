@@ -0,0 +1,184 @@
+package manager
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+//PriorityPolicy makes a pool run its queued tasks by priority instead of in the order they were submitted
+type PriorityPolicy struct {
+	//Aging raises the priority of a queued task by one level every Aging so low priority tasks are not starved,
+	//0 means the priority of the tasks never changes
+	Aging time.Duration
+}
+
+func (policy *PriorityPolicy) validate() error {
+	if policy.Aging < 0 {
+		return errors.New("priority Aging has to be greater or equal to 0")
+	}
+	return nil
+}
+
+//priorityTicket is what a priority pool hands to its workers: each ticket stands for a queued task and
+//the worker picking it up runs the task with the highest priority at that moment
+type priorityTicket struct{}
+
+//prioritizedTask is a task waiting in a priority queue
+type prioritizedTask struct {
+	task  *task
+	rank  float64
+	seq   uint64
+	index int
+}
+
+//priorityQueue keeps the queued tasks of a priority pool, highest rank first and in submission order for the same rank.
+//The rank of a task is its priority plus the levels it gained by aging, which keeps the order of the tasks
+//already queued as every one of them ages at the same pace
+type priorityQueue struct {
+	mutex   sync.Mutex
+	aging   time.Duration
+	epoch   time.Time
+	items   []*prioritizedTask
+	seq     uint64
+	byLevel map[int]int
+}
+
+func newPriorityQueue(policy *PriorityPolicy) *priorityQueue {
+	return &priorityQueue{aging: policy.Aging, epoch: time.Now(), byLevel: make(map[int]int)}
+}
+
+func (queue *priorityQueue) Len() int {
+	return len(queue.items)
+}
+
+func (queue *priorityQueue) Less(i, j int) bool {
+	if queue.items[i].rank != queue.items[j].rank {
+		return queue.items[i].rank > queue.items[j].rank
+	}
+	return queue.items[i].seq < queue.items[j].seq
+}
+
+func (queue *priorityQueue) Swap(i, j int) {
+	queue.items[i], queue.items[j] = queue.items[j], queue.items[i]
+	queue.items[i].index = i
+	queue.items[j].index = j
+}
+
+func (queue *priorityQueue) Push(item interface{}) {
+	prioritized := item.(*prioritizedTask)
+	prioritized.index = len(queue.items)
+	queue.items = append(queue.items, prioritized)
+}
+
+func (queue *priorityQueue) Pop() interface{} {
+	last := len(queue.items) - 1
+	prioritized := queue.items[last]
+	queue.items[last] = nil
+	queue.items = queue.items[:last]
+	return prioritized
+}
+
+//push queues task with its priority and returns the handle needed to remove it
+func (queue *priorityQueue) push(task *task) *prioritizedTask {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	rank := float64(task.priority)
+	if queue.aging > 0 {
+		rank -= float64(time.Since(queue.epoch)) / float64(queue.aging)
+	}
+	queue.seq++
+	prioritized := &prioritizedTask{task: task, rank: rank, seq: queue.seq}
+	heap.Push(queue, prioritized)
+	queue.byLevel[task.priority]++
+	return prioritized
+}
+
+//pop removes the task with the highest priority, nil if the queue is empty
+func (queue *priorityQueue) pop() *task {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	if len(queue.items) == 0 {
+		return nil
+	}
+	task := heap.Pop(queue).(*prioritizedTask).task
+	queue.forget(task)
+	return task
+}
+
+//remove takes out of the queue a task that could not be handed to the pool
+func (queue *priorityQueue) remove(prioritized *prioritizedTask) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	if prioritized.index < 0 || prioritized.index >= len(queue.items) || queue.items[prioritized.index] != prioritized {
+		return
+	}
+	heap.Remove(queue, prioritized.index)
+	queue.forget(prioritized.task)
+}
+
+//forget removes task from the depth of its level, it has to be called holding the mutex
+func (queue *priorityQueue) forget(task *task) {
+	if queue.byLevel[task.priority]--; queue.byLevel[task.priority] == 0 {
+		delete(queue.byLevel, task.priority)
+	}
+}
+
+//depths returns the amount of queued tasks by priority level
+func (queue *priorityQueue) depths() map[int]int {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	depths := make(map[int]int, len(queue.byLevel))
+	for level, depth := range queue.byLevel {
+		depths[level] = depth
+	}
+	return depths
+}
+
+//SubmitWithPriority enqueues a new task to be accomplished by the desired priority pool, tasks with a higher priority
+//are run first. It fails for pools created without a PriorityPolicy
+func (manager *Manager) SubmitWithPriority(poolID string, priority int, data interface{}) error {
+	if data == nil {
+		return errors.New("data cannot be nil")
+	}
+	_, record, ok := manager.lookup(poolID)
+	if !ok {
+		return errors.New(fmt.Sprintf("No pool exists for poolID: %s", poolID))
+	}
+	if record.priority == nil {
+		return errors.New(fmt.Sprintf("pool `%s` does not support priorities", poolID))
+	}
+	return manager.enqueue(poolID, &task{ctx: context.Background(), data: data, priority: priority})
+}
+
+//dispatch hands task to pool, through the priority queue for priority pools
+func (record *poolRecord) dispatch(pool taskAdder, task *task) error {
+	record.track(task)
+	if record.priority == nil {
+		if err := pool.AddTask(task); err != nil {
+			record.untrack(task)
+			return err
+		}
+		return nil
+	}
+	prioritized := record.priority.push(task)
+	if err := pool.AddTask(priorityTicket{}); err != nil {
+		record.priority.remove(prioritized)
+		record.untrack(task)
+		return err
+	}
+	return nil
+}
+
+//unwrap returns the task handed to a worker as data, for priority pools the one with the highest priority.
+//The task is nil for a ticket left without task, the bool is false for data not submitted through the manager
+func (record *poolRecord) unwrap(data interface{}) (*task, bool) {
+	if _, ok := data.(priorityTicket); ok && record.priority != nil {
+		return record.priority.pop(), true
+	}
+	task, ok := data.(*task)
+	return task, ok
+}
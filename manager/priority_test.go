@@ -0,0 +1,67 @@
+package manager
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestManager_SubmitWithPriority(t *testing.T) {
+	manager := createManagerMock(2)
+	manager.AddPool("fifo", 1, 10, false)
+	if err := manager.SubmitWithPriority("fifo", 1, "task"); err == nil {
+		t.Error("SubmitWithPriority() must fail for a pool without priorities")
+	}
+	if err := manager.AddPoolWithOptions("urgent", PoolOptions{MaxJobsInQueue: 10, Priority: &PriorityPolicy{Aging: -time.Second}}); err == nil {
+		t.Error("AddPoolWithOptions() must fail for a negative aging")
+	}
+	manager.AddPoolWithOptions("urgent", PoolOptions{InitialWorkers: 1, MaxJobsInQueue: 10, Priority: &PriorityPolicy{}})
+	results := make(chan Result, 10)
+	manager.SetResultsChannel("urgent", results)
+	manager.SetFunc("urgent", func(interface{}) bool { return true })
+
+	for _, submission := range []struct {
+		priority int
+		data     string
+	}{{0, "low"}, {5, "high"}, {1, "normal"}, {5, "high again"}, {10, "urgent"}} {
+		if err := manager.SubmitWithPriority("urgent", submission.priority, submission.data); err != nil {
+			t.Fatalf("SubmitWithPriority() error = %v", err)
+		}
+	}
+	manager.AddTaskToPool("urgent", "default")
+	stats, _ := manager.PoolStats("urgent")
+	if want := map[int]int{0: 2, 1: 1, 5: 2, 10: 1}; !reflect.DeepEqual(stats.QueuedByPriority, want) {
+		t.Errorf("QueuedByPriority = %v, want %v", stats.QueuedByPriority, want)
+	}
+
+	manager.StartPool("urgent")
+	manager.StopPool("urgent")
+	close(results)
+	processed := []interface{}{}
+	for result := range results {
+		processed = append(processed, result.Data)
+	}
+	if want := []interface{}{"urgent", "high", "high again", "normal", "low", "default"}; !reflect.DeepEqual(processed, want) {
+		t.Errorf("tasks processed in order %v, want %v", processed, want)
+	}
+	if stats, _ = manager.PoolStats("urgent"); len(stats.QueuedByPriority) != 0 {
+		t.Errorf("QueuedByPriority once processed = %v", stats.QueuedByPriority)
+	}
+}
+
+func TestManager_PriorityAging(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPoolWithOptions("urgent", PoolOptions{InitialWorkers: 1, MaxJobsInQueue: 10, Priority: &PriorityPolicy{Aging: time.Millisecond}})
+	results := make(chan Result, 2)
+	manager.SetResultsChannel("urgent", results)
+	manager.SetFunc("urgent", func(interface{}) bool { return true })
+
+	manager.SubmitWithPriority("urgent", 0, "old")
+	time.Sleep(time.Millisecond * 30)
+	manager.SubmitWithPriority("urgent", 5, "new")
+	manager.StartPool("urgent")
+	manager.StopPool("urgent")
+	if first := <-results; first.Data != "old" {
+		t.Errorf("first task processed = %v, want the aged one", first.Data)
+	}
+}
@@ -0,0 +1,148 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+//RateLimit bounds how many tasks of a pool start per second, whatever the amount of workers
+type RateLimit struct {
+	//Rate is the amount of tasks per second
+	Rate float64
+	//Burst is the amount of tasks that can start at once after the pool was idle, 0 means 1
+	Burst int
+}
+
+func (limit *RateLimit) validate() error {
+	if limit.Rate <= 0 {
+		return errors.New("rate limit Rate has to be greater than 0")
+	}
+	if limit.Burst < 0 {
+		return errors.New("rate limit Burst has to be greater or equal to 0")
+	}
+	return nil
+}
+
+func (limit *RateLimit) burst() float64 {
+	if limit.Burst == 0 {
+		return 1
+	}
+	return float64(limit.Burst)
+}
+
+//tokenBucket is a token bucket filled at rate tokens per second up to burst tokens. Tokens are reserved ahead,
+//so the bucket goes negative while tasks wait for their turn
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit *RateLimit) *tokenBucket {
+	return &tokenBucket{rate: limit.Rate, burst: limit.burst(), tokens: limit.burst(), last: time.Now()}
+}
+
+//refill adds the tokens earned since the last refill, it has to be called holding the mutex
+func (bucket *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(bucket.last); elapsed > 0 {
+		bucket.tokens += elapsed.Seconds() * bucket.rate
+		bucket.last = now
+	}
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+}
+
+//reserve takes a token and returns how long to wait until it is earned
+func (bucket *tokenBucket) reserve(now time.Time) time.Duration {
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+	bucket.refill(now)
+	bucket.tokens--
+	if bucket.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-bucket.tokens / bucket.rate * float64(time.Second))
+}
+
+//giveBack returns a token reserved by a task that did not run
+func (bucket *tokenBucket) giveBack() {
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+	bucket.tokens++
+	bucket.refill(time.Now())
+}
+
+//set changes the rate and burst of the bucket keeping the tokens already earned or reserved
+func (bucket *tokenBucket) set(limit *RateLimit) {
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+	bucket.refill(time.Now())
+	bucket.rate, bucket.burst = limit.Rate, limit.burst()
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+}
+
+//SetRateLimit bounds how many tasks of poolID start per second, nil removes the bound.
+//The tasks waiting for their turn keep holding their worker
+func (manager *Manager) SetRateLimit(poolID string, limit *RateLimit) error {
+	_, record, ok := manager.lookup(poolID)
+	if !ok {
+		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
+	}
+	if limit != nil {
+		if err := limit.validate(); err != nil {
+			return err
+		}
+	}
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.setRateLimit(limit)
+	return nil
+}
+
+//setRateLimit updates the token bucket of the pool, it has to be called holding the mutex
+func (record *poolRecord) setRateLimit(limit *RateLimit) {
+	switch {
+	case limit == nil:
+		record.limiter = nil
+	case record.limiter == nil:
+		record.limiter = newTokenBucket(limit)
+	default:
+		record.limiter.set(limit)
+	}
+}
+
+//throttle waits until the rate limit of the pool lets a task start, it returns the error of ctx if it is done first
+func (record *poolRecord) throttle(ctx context.Context) error {
+	record.mutex.Lock()
+	limiter := record.limiter
+	record.mutex.Unlock()
+	if limiter == nil {
+		return nil
+	}
+	startedAt := time.Now()
+	wait := limiter.reserve(startedAt)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		limiter.giveBack()
+		err = ctx.Err()
+	}
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.throttled += time.Since(startedAt)
+	return err
+}
@@ -0,0 +1,109 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket_reserve(t *testing.T) {
+	start := time.Now()
+	bucket := newTokenBucket(&RateLimit{Rate: 10, Burst: 2})
+	bucket.last = start
+	tests := []struct {
+		name  string
+		after time.Duration
+		want  time.Duration
+	}{
+		{"Takes the first token of the burst", 0, 0},
+		{"Takes the second token of the burst", 0, 0},
+		{"Waits for the next token", 0, time.Millisecond * 100},
+		{"Waits behind the reserved tokens", 0, time.Millisecond * 200},
+		{"Waits less as the tokens are earned", time.Millisecond * 150, time.Millisecond * 150},
+		{"Does not earn more tokens than the burst", time.Second * 10, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bucket.reserve(start.Add(tt.after)); got < tt.want-time.Millisecond || got > tt.want+time.Millisecond {
+				t.Errorf("reserve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if bucket.tokens != 1 {
+		t.Errorf("tokens left = %v, want 1", bucket.tokens)
+	}
+}
+
+func TestManager_SetRateLimit(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("payments", 1, 10, false)
+	tests := []struct {
+		name    string
+		poolID  string
+		limit   *RateLimit
+		wantErr bool
+	}{
+		{"Returns error if the pool does not exist", "refunds", &RateLimit{Rate: 1}, true},
+		{"Returns error for a rate of 0", "payments", &RateLimit{}, true},
+		{"Returns error for a negative burst", "payments", &RateLimit{Rate: 1, Burst: -1}, true},
+		{"Sets the rate limit", "payments", &RateLimit{Rate: 1, Burst: 5}, false},
+		{"Adjusts the rate limit", "payments", &RateLimit{Rate: 2, Burst: 1}, false},
+		{"Removes the rate limit", "payments", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := manager.SetRateLimit(tt.poolID, tt.limit); (err != nil) != tt.wantErr {
+				t.Errorf("SetRateLimit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManager_RateLimit(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPoolWithOptions("payments", PoolOptions{InitialWorkers: 4, MaxJobsInQueue: 10, RateLimit: &RateLimit{Rate: 50, Burst: 1}})
+	manager.SetFunc("payments", func(interface{}) bool { return true })
+	manager.StartPool("payments")
+
+	startedAt := time.Now()
+	for i := 0; i < 6; i++ {
+		manager.AddTaskToPool("payments", i)
+	}
+	waitFor(func() bool { stats, _ := manager.PoolStats("payments"); return stats.CompletedTasks == 6 })
+	if elapsed := time.Since(startedAt); elapsed < time.Millisecond*90 {
+		t.Errorf("6 tasks at 50 tasks/s ran in %v", elapsed)
+	}
+	if stats, _ := manager.PoolStats("payments"); stats.ThrottledTime < time.Millisecond*90 {
+		t.Errorf("ThrottledTime = %v", stats.ThrottledTime)
+	}
+
+	manager.SetRateLimit("payments", &RateLimit{Rate: 1})
+	manager.AddTaskToPool("payments", "first")
+	manager.AddTaskToPool("payments", "waiting")
+	if !waitFor(func() bool {
+		stats, _ := manager.PoolStats("payments")
+		return stats.CompletedTasks == 7 && stats.InFlightTasks == 1
+	}) {
+		t.Fatal("the task must wait for the rate limit")
+	}
+	manager.KillPool("payments")
+	if stats, _ := manager.PoolStats("payments"); stats.CancelledTasks != 1 {
+		t.Errorf("CancelledTasks = %d, the task waiting for the rate limit must be cancelled", stats.CancelledTasks)
+	}
+}
+
+func TestPoolRecord_throttle(t *testing.T) {
+	record := newPoolRecord("payments")
+	record.setRateLimit(&RateLimit{Rate: 1})
+	if err := record.throttle(context.Background()); err != nil {
+		t.Fatalf("throttle() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if err := record.throttle(ctx); err != context.DeadlineExceeded {
+		t.Errorf("throttle() error = %v, want the error of the context", err)
+	}
+	if record.limiter.tokens < -0.1 {
+		t.Errorf("tokens = %v, the token of a task that did not run must be given back", record.limiter.tokens)
+	}
+}
@@ -0,0 +1,152 @@
+package manager
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+//Backoff returns how long to wait before running a task again after its failed attempt number {attempt}
+type Backoff func(attempt int) time.Duration
+
+//ConstantBackoff waits the same delay before every retry
+func ConstantBackoff(delay time.Duration) Backoff {
+	return func(int) time.Duration {
+		return delay
+	}
+}
+
+//ExponentialBackoff waits initial before the first retry and multiplies the delay by multiplier on every
+//following one, the delay never goes over max. A multiplier lower than 1 defaults to 2
+func ExponentialBackoff(initial time.Duration, max time.Duration, multiplier float64) Backoff {
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	return func(attempt int) time.Duration {
+		delay := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+		if delay > float64(max) {
+			return max
+		}
+		return time.Duration(delay)
+	}
+}
+
+//JitteredBackoff randomizes the delays of backoff by up to fraction of their value in both directions,
+//so tasks failing together are not retried together
+func JitteredBackoff(backoff Backoff, fraction float64) Backoff {
+	return func(attempt int) time.Duration {
+		delay := backoff(attempt)
+		jitter := float64(delay) * fraction * (2*rand.Float64() - 1)
+		if delay += time.Duration(jitter); delay < 0 {
+			return 0
+		}
+		return delay
+	}
+}
+
+//RetryPolicy defines how the failed tasks of a pool are run again
+type RetryPolicy struct {
+	//MaxAttempts is the maximum amount of times a task is run, including the first one
+	MaxAttempts int
+	//Backoff gives the delay before each retry, nil retries right away
+	Backoff Backoff
+	//Retryable tells whether a task failing with err may be retried, nil retries every error not marked as Permanent
+	Retryable func(err error) bool
+}
+
+func (policy *RetryPolicy) validate() error {
+	if policy.MaxAttempts < 1 {
+		return errors.New("retry MaxAttempts has to be greater than 0")
+	}
+	return nil
+}
+
+//retries tells whether a task failing with err on its attempt number {attempt} has to be run again
+func (policy *RetryPolicy) retries(attempt int, err error) bool {
+	if policy == nil || attempt >= policy.MaxAttempts {
+		return false
+	}
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+	return policy.Retryable == nil || policy.Retryable(err)
+}
+
+//delay returns how long to wait before running again a task that failed on its attempt number {attempt}
+func (policy *RetryPolicy) delay(attempt int) time.Duration {
+	if policy.Backoff == nil {
+		return 0
+	}
+	return policy.Backoff(attempt)
+}
+
+//policy returns the retry policy of the pool
+func (record *poolRecord) policy() *RetryPolicy {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	return record.retryPolicy
+}
+
+type permanentError struct {
+	err error
+}
+
+func (err *permanentError) Error() string {
+	return err.err.Error()
+}
+
+func (err *permanentError) Unwrap() error {
+	return err.err
+}
+
+//Permanent marks err so the task returning it is never retried, whatever the retry policy of the pool
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+type attemptKey struct{}
+
+//Attempt returns the attempt number of the task run with ctx, starting at 1. It returns 0 if ctx is not the context of a task
+func Attempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt
+}
+
+//retry runs task again on pool once the backoff delay of its failed attempt is over.
+//The task stays pending meanwhile, it is cancelled if its context or the context of the pool is done before
+func (record *poolRecord) retry(pool taskAdder, task *task, delay time.Duration) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	outcome := outcomeCancelled
+	select {
+	case <-timer.C:
+		task.attempt++
+		err := record.dispatch(pool, task)
+		if err == nil {
+			return
+		}
+		task.discard()
+		task.attempt--
+		record.bury(task, err, FailureRejected)
+		outcome = outcomeFailed
+		record.abandon(task, err, outcome)
+	case <-task.ctx.Done():
+		record.abandon(task, task.ctx.Err(), outcome)
+	case <-task.poolCtx.Done():
+		record.abandon(task, task.poolCtx.Err(), outcome)
+	}
+	record.conclude(pool, task, outcome)
+}
+
+//abandon settles a task waiting to be retried that is not going to run again
+func (record *poolRecord) abandon(task *task, err error, outcome taskOutcome) {
+	record.deliver(task, nil, err)
+	record.begin()
+	record.finish(outcome)
+}
@@ -0,0 +1,203 @@
+package manager
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		want    []time.Duration
+	}{
+		{
+			"Constant backoff waits the same delay on every attempt",
+			ConstantBackoff(time.Millisecond * 5),
+			[]time.Duration{time.Millisecond * 5, time.Millisecond * 5, time.Millisecond * 5},
+		},
+		{
+			"Exponential backoff multiplies the delay up to the max",
+			ExponentialBackoff(time.Millisecond, time.Millisecond*10, 2),
+			[]time.Duration{time.Millisecond, time.Millisecond * 2, time.Millisecond * 4, time.Millisecond * 8, time.Millisecond * 10},
+		},
+		{
+			"Exponential backoff defaults the multiplier to 2",
+			ExponentialBackoff(time.Millisecond, time.Second, 0),
+			[]time.Duration{time.Millisecond, time.Millisecond * 2, time.Millisecond * 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				if got := tt.backoff(i + 1); got != want {
+					t.Errorf("backoff(%d) = %v, want %v", i+1, got, want)
+				}
+			}
+		})
+	}
+
+	jittered := JitteredBackoff(ConstantBackoff(time.Millisecond*100), 0.5)
+	for i := 1; i < 50; i++ {
+		if got := jittered(i); got < time.Millisecond*50 || got > time.Millisecond*150 {
+			t.Errorf("jittered backoff(%d) = %v, want between 50ms and 150ms", i, got)
+		}
+	}
+}
+
+func TestManager_AddPoolWithOptions(t *testing.T) {
+	type args struct {
+		poolID  string
+		options PoolOptions
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			"Returns error if maxJobsInQueue is lower than 1",
+			args{"slowProcessing", PoolOptions{InitialWorkers: 1}},
+			true,
+		},
+		{
+			"Returns error if the retry policy does not allow any attempt",
+			args{"slowProcessing", PoolOptions{InitialWorkers: 1, MaxJobsInQueue: 1, Retry: &RetryPolicy{}}},
+			true,
+		},
+		{
+			"Creates a pool with a retry policy",
+			args{"slowProcessing", PoolOptions{InitialWorkers: 1, MaxJobsInQueue: 1, Retry: &RetryPolicy{MaxAttempts: 3}}},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := createManagerMock(1)
+			if err := manager.AddPoolWithOptions(tt.args.poolID, tt.args.options); (err != nil) != tt.wantErr {
+				t.Errorf("AddPoolWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManager_Retry(t *testing.T) {
+	errUnavailable := errors.New("service unavailable")
+	tests := []struct {
+		name          string
+		policy        RetryPolicy
+		workerFunc    func(context.Context, interface{}) error
+		wantAttempts  int
+		wantCompleted int
+		wantFailed    int
+		wantRetried   int
+	}{
+		{
+			"Retries a failed task until it succeeds",
+			RetryPolicy{MaxAttempts: 3, Backoff: ConstantBackoff(time.Millisecond)},
+			func(ctx context.Context, data interface{}) error {
+				if Attempt(ctx) < 3 {
+					return errUnavailable
+				}
+				return nil
+			},
+			3, 1, 0, 2,
+		},
+		{
+			"Gives up once the attempts are exhausted",
+			RetryPolicy{MaxAttempts: 2, Backoff: ExponentialBackoff(time.Millisecond, time.Millisecond*5, 2)},
+			func(context.Context, interface{}) error { return errUnavailable },
+			2, 0, 1, 1,
+		},
+		{
+			"Does not retry permanent errors",
+			RetryPolicy{MaxAttempts: 3},
+			func(context.Context, interface{}) error { return Permanent(errUnavailable) },
+			1, 0, 1, 0,
+		},
+		{
+			"Does not retry errors the policy does not classify as retryable",
+			RetryPolicy{MaxAttempts: 3, Retryable: func(err error) bool { return err != errUnavailable }},
+			func(context.Context, interface{}) error { return errUnavailable },
+			1, 0, 1, 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := createManagerMock(1)
+			policy := tt.policy
+			manager.AddPoolWithOptions("slowProcessing", PoolOptions{InitialWorkers: 1, MaxJobsInQueue: 10, Retry: &policy})
+			attempts := make(chan int, 10)
+			manager.SetFuncContext("slowProcessing", func(ctx context.Context, data interface{}) error {
+				attempts <- Attempt(ctx)
+				return tt.workerFunc(ctx, data)
+			})
+			manager.StartPool("slowProcessing")
+			future, _ := manager.SubmitWithResult("slowProcessing", "task")
+			manager.StopPool("slowProcessing")
+
+			close(attempts)
+			got := 0
+			for attempt := range attempts {
+				if got++; attempt != got {
+					t.Errorf("Attempt() = %d, want %d", attempt, got)
+				}
+			}
+			if got != tt.wantAttempts {
+				t.Errorf("task run %d times, want %d", got, tt.wantAttempts)
+			}
+			if _, err := future.Await(context.Background()); (err != nil) != (tt.wantFailed > 0) {
+				t.Errorf("Await() error = %v", err)
+			}
+			stats, _ := manager.PoolStats("slowProcessing")
+			if stats.CompletedTasks != tt.wantCompleted || stats.FailedTasks != tt.wantFailed || stats.RetriedTasks != tt.wantRetried {
+				t.Errorf("PoolStats() = %+v", stats)
+			}
+		})
+	}
+}
+
+func TestManager_RetryFailedBoolTask(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPoolWithOptions("slowProcessing", PoolOptions{InitialWorkers: 1, MaxJobsInQueue: 10, Retry: &RetryPolicy{MaxAttempts: 2}})
+	runs := 0
+	manager.SetFunc("slowProcessing", func(interface{}) bool {
+		runs++
+		return runs > 1
+	})
+	manager.StartPool("slowProcessing")
+	manager.AddTaskToPool("slowProcessing", "task")
+	manager.StopPool("slowProcessing")
+	if stats, _ := manager.PoolStats("slowProcessing"); runs != 2 || stats.CompletedTasks != 1 || stats.RetriedTasks != 1 {
+		t.Errorf("expected the task returning false to be retried once, runs %d, stats %+v", runs, stats)
+	}
+}
+
+func TestManager_KillPoolCancelsRetries(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPoolWithOptions("slowProcessing", PoolOptions{
+		InitialWorkers: 1,
+		MaxJobsInQueue: 10,
+		Retry:          &RetryPolicy{MaxAttempts: 5, Backoff: ConstantBackoff(time.Hour)},
+	})
+	failed := make(chan struct{}, 1)
+	manager.SetFunc("slowProcessing", func(interface{}) bool {
+		failed <- struct{}{}
+		return false
+	})
+	manager.StartPool("slowProcessing")
+	future, _ := manager.SubmitWithResult("slowProcessing", "task")
+	<-failed
+
+	if err := manager.KillPool("slowProcessing"); err != nil {
+		t.Fatalf("KillPool() error = %v", err)
+	}
+	if _, err := future.Await(context.Background()); err != context.Canceled {
+		t.Errorf("Await() error = %v, want %v", err, context.Canceled)
+	}
+	if stats, _ := manager.PoolStats("slowProcessing"); stats.CancelledTasks != 1 || stats.RetriedTasks != 1 || stats.State != PoolStopped {
+		t.Errorf("PoolStats() after KillPool() = %+v", stats)
+	}
+}
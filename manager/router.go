@@ -0,0 +1,189 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"math/rand"
+	"sync"
+)
+
+//ErrNoRoute is returned when no rule of the router matches a task and no default route is defined
+var ErrNoRoute = errors.New("no route matches the task")
+
+//RoutedTask is a task submitted through Manager.Route, the router picks its pool from its data, labels or headers
+type RoutedTask struct {
+	Data    interface{}
+	Labels  map[string]string
+	Headers map[string]string
+}
+
+//Predicate tells whether a rule of the router applies to a task
+type Predicate func(task RoutedTask) bool
+
+//MatchData applies a rule to the tasks whose data satisfies match
+func MatchData(match func(data interface{}) bool) Predicate {
+	return func(task RoutedTask) bool {
+		return match(task.Data)
+	}
+}
+
+//MatchLabel applies a rule to the tasks labeled with key set to value
+func MatchLabel(key string, value string) Predicate {
+	return func(task RoutedTask) bool {
+		label, ok := task.Labels[key]
+		return ok && label == value
+	}
+}
+
+//MatchHeader applies a rule to the tasks whose header key is set to value
+func MatchHeader(key string, value string) Predicate {
+	return func(task RoutedTask) bool {
+		header, ok := task.Headers[key]
+		return ok && header == value
+	}
+}
+
+//WeightedRoute is a pool receiving a share of the tasks of a rule proportional to its weight
+type WeightedRoute struct {
+	PoolID string
+	Weight int
+}
+
+//routes is the destination of a rule, a single pool or a weighted split
+type routes struct {
+	targets []WeightedRoute
+	total   int
+}
+
+func newRoutes(targets []WeightedRoute) (routes, error) {
+	if len(targets) == 0 {
+		return routes{}, errors.New("a route needs at least a pool")
+	}
+	total := 0
+	for _, target := range targets {
+		if target.PoolID == "" {
+			return routes{}, errors.New("PoolId cannot be empty")
+		}
+		if target.Weight < 1 {
+			return routes{}, errors.New(fmt.Sprintf("weight of pool `%s` has to be greater than 0", target.PoolID))
+		}
+		total += target.Weight
+	}
+	return routes{targets: append([]WeightedRoute{}, targets...), total: total}, nil
+}
+
+//pick returns the pool of a task, choosing it randomly by weight on splits
+func (routes routes) pick() string {
+	if len(routes.targets) == 1 {
+		return routes.targets[0].PoolID
+	}
+	n := rand.Intn(routes.total)
+	for _, target := range routes.targets {
+		if n -= target.Weight; n < 0 {
+			return target.PoolID
+		}
+	}
+	return routes.targets[len(routes.targets)-1].PoolID
+}
+
+type rule struct {
+	predicate Predicate
+	routes    routes
+}
+
+//Router maps tasks to pools: rules are checked in the order they were added and the first one matching
+//a task picks its pool, tasks matching no rule go to the default route
+type Router struct {
+	mutex        sync.RWMutex
+	rules        []rule
+	defaultRoute *routes
+}
+
+//NewRouter returns a router without rules
+func NewRouter() *Router {
+	return &Router{}
+}
+
+//When routes the tasks matching predicate to poolID
+func (router *Router) When(predicate Predicate, poolID string) error {
+	return router.WhenSplit(predicate, WeightedRoute{PoolID: poolID, Weight: 1})
+}
+
+//WhenSplit spreads the tasks matching predicate over the given pools according to their weights
+func (router *Router) WhenSplit(predicate Predicate, targets ...WeightedRoute) error {
+	if predicate == nil {
+		return errors.New("predicate cannot be nil")
+	}
+	routes, err := newRoutes(targets)
+	if err != nil {
+		return err
+	}
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+	router.rules = append(router.rules, rule{predicate: predicate, routes: routes})
+	return nil
+}
+
+//Default routes the tasks matching no rule to poolID
+func (router *Router) Default(poolID string) error {
+	return router.DefaultSplit(WeightedRoute{PoolID: poolID, Weight: 1})
+}
+
+//DefaultSplit spreads the tasks matching no rule over the given pools according to their weights
+func (router *Router) DefaultSplit(targets ...WeightedRoute) error {
+	routes, err := newRoutes(targets)
+	if err != nil {
+		return err
+	}
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+	router.defaultRoute = &routes
+	return nil
+}
+
+//Resolve returns the pool task is routed to
+func (router *Router) Resolve(task RoutedTask) (string, error) {
+	router.mutex.RLock()
+	defer router.mutex.RUnlock()
+	for _, rule := range router.rules {
+		if rule.predicate(task) {
+			return rule.routes.pick(), nil
+		}
+	}
+	if router.defaultRoute == nil {
+		return "", ErrNoRoute
+	}
+	return router.defaultRoute.pick(), nil
+}
+
+//SetRouter defines the router used by Route to pick the pool of each task, nil removes it
+func (manager *Manager) SetRouter(router *Router) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	manager.router = router
+}
+
+//Route enqueues task to the pool picked by the router of the manager and returns the id of that pool
+func (manager *Manager) Route(task RoutedTask) (string, error) {
+	return manager.RouteContext(context.Background(), task)
+}
+
+//RouteContext enqueues task to the pool picked by the router of the manager and returns the id of that pool.
+//Cancelling ctx cancels the task as AddTaskToPoolContext does
+func (manager *Manager) RouteContext(ctx context.Context, task RoutedTask) (string, error) {
+	manager.mutex.RLock()
+	router := manager.router
+	manager.mutex.RUnlock()
+	if router == nil {
+		return "", errors.New("no router has been set")
+	}
+	poolID, err := router.Resolve(task)
+	if err != nil {
+		return "", err
+	}
+	if err := manager.AddTaskToPoolContext(ctx, poolID, task.Data); err != nil {
+		return "", err
+	}
+	return poolID, nil
+}
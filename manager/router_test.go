@@ -0,0 +1,124 @@
+package manager
+
+import (
+	"testing"
+)
+
+func TestRouter_Resolve(t *testing.T) {
+	router := NewRouter()
+	router.When(MatchData(func(data interface{}) bool {
+		img, ok := data.(imageTask)
+		return ok && img.size > 5
+	}), "big-size")
+	router.When(MatchLabel("priority", "high"), "fast-lane")
+	router.When(MatchHeader("tenant", "acme"), "acme")
+	tests := []struct {
+		name    string
+		task    RoutedTask
+		want    string
+		wantErr error
+	}{
+		{
+			"Routes by payload",
+			RoutedTask{Data: imageTask{path: "big.png", size: 8}},
+			"big-size",
+			nil,
+		},
+		{
+			"Routes by label",
+			RoutedTask{Data: imageTask{path: "small.png", size: 2}, Labels: map[string]string{"priority": "high"}},
+			"fast-lane",
+			nil,
+		},
+		{
+			"Routes by header",
+			RoutedTask{Data: "report", Headers: map[string]string{"tenant": "acme"}},
+			"acme",
+			nil,
+		},
+		{
+			"The first matching rule wins",
+			RoutedTask{Data: imageTask{path: "big.png", size: 8}, Labels: map[string]string{"priority": "high"}},
+			"big-size",
+			nil,
+		},
+		{
+			"Returns ErrNoRoute if no rule matches and there is no default route",
+			RoutedTask{Data: "report"},
+			"",
+			ErrNoRoute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := router.Resolve(tt.task)
+			if got != tt.want || err != tt.wantErr {
+				t.Errorf("Resolve() = %v, %v, want %v, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	router.Default("low-size")
+	if got, _ := router.Resolve(RoutedTask{Data: "report"}); got != "low-size" {
+		t.Errorf("Resolve() with a default route = %v, want low-size", got)
+	}
+}
+
+func TestRouter_WeightedSplit(t *testing.T) {
+	router := NewRouter()
+	if err := router.DefaultSplit(WeightedRoute{PoolID: "canary", Weight: 0}); err == nil {
+		t.Error("DefaultSplit() must fail for a weight lower than 1")
+	}
+	if err := router.WhenSplit(nil, WeightedRoute{PoolID: "canary", Weight: 1}); err == nil {
+		t.Error("WhenSplit() must fail for a nil predicate")
+	}
+	router.DefaultSplit(WeightedRoute{PoolID: "stable", Weight: 3}, WeightedRoute{PoolID: "canary", Weight: 1})
+	routed := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		poolID, _ := router.Resolve(RoutedTask{Data: i})
+		routed[poolID]++
+	}
+	if len(routed) != 2 || routed["canary"] < 800 || routed["canary"] > 1200 {
+		t.Errorf("expected a quarter of the tasks to be routed to canary, got %v", routed)
+	}
+}
+
+func TestManager_Route(t *testing.T) {
+	manager := createManagerMock(2)
+	if _, err := manager.Route(RoutedTask{Data: "report"}); err == nil {
+		t.Error("Route() must fail if no router has been set")
+	}
+	manager.AddPool("big-size", 1, 10, false)
+	manager.AddPool("low-size", 1, 10, false)
+	processed := map[string][]interface{}{}
+	results := make(chan Result, 2)
+	for _, poolID := range []string{"big-size", "low-size"} {
+		manager.SetResultsChannel(poolID, results)
+		manager.SetFunc(poolID, func(interface{}) bool { return true })
+		manager.StartPool(poolID)
+	}
+	router := NewRouter()
+	router.When(MatchData(func(data interface{}) bool { return data.(imageTask).size > 5 }), "big-size")
+	router.Default("low-size")
+	manager.SetRouter(router)
+
+	if poolID, err := manager.Route(RoutedTask{Data: imageTask{path: "big.png", size: 8}}); poolID != "big-size" || err != nil {
+		t.Errorf("Route() = %v, %v", poolID, err)
+	}
+	if poolID, err := manager.Route(RoutedTask{Data: imageTask{path: "small.png", size: 2}}); poolID != "low-size" || err != nil {
+		t.Errorf("Route() = %v, %v", poolID, err)
+	}
+	for i := 0; i < 2; i++ {
+		result := <-results
+		processed[result.PoolID] = append(processed[result.PoolID], result.Data)
+	}
+	if len(processed["big-size"]) != 1 || processed["big-size"][0].(imageTask).path != "big.png" || len(processed["low-size"]) != 1 {
+		t.Errorf("tasks processed by pool = %v", processed)
+	}
+
+	manager.StopPool("big-size")
+	if _, err := manager.Route(RoutedTask{Data: imageTask{path: "big.png", size: 8}}); err == nil {
+		t.Error("Route() to a stopped pool must fail")
+	}
+	manager.StopPool("low-size")
+}
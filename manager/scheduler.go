@@ -0,0 +1,295 @@
+package manager
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+//TaskID identifies a task scheduled to be submitted later
+type TaskID uint64
+
+//ScheduledTask is a task waiting to be submitted to its pool
+type ScheduledTask struct {
+	ID     TaskID
+	PoolID string
+	Data   interface{}
+	//Due is when the task is submitted to the pool
+	Due time.Time
+}
+
+//timerHeap keeps the scheduled tasks sorted by due time, soonest first
+type timerHeap []*scheduledEntry
+
+type scheduledEntry struct {
+	ScheduledTask
+	index int
+}
+
+func (timers timerHeap) Len() int {
+	return len(timers)
+}
+
+func (timers timerHeap) Less(i, j int) bool {
+	if timers[i].Due.Equal(timers[j].Due) {
+		return timers[i].ID < timers[j].ID
+	}
+	return timers[i].Due.Before(timers[j].Due)
+}
+
+func (timers timerHeap) Swap(i, j int) {
+	timers[i], timers[j] = timers[j], timers[i]
+	timers[i].index = i
+	timers[j].index = j
+}
+
+func (timers *timerHeap) Push(item interface{}) {
+	entry := item.(*scheduledEntry)
+	entry.index = len(*timers)
+	*timers = append(*timers, entry)
+}
+
+func (timers *timerHeap) Pop() interface{} {
+	old := *timers
+	last := len(old) - 1
+	entry := old[last]
+	old[last] = nil
+	*timers = old[:last]
+	return entry
+}
+
+//scheduler submits the scheduled tasks of a manager once they are due, a single goroutine sleeps until the soonest one
+type scheduler struct {
+	mutex   sync.Mutex
+	timers  timerHeap
+	entries map[TaskID]*scheduledEntry
+	lastID  TaskID
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	submit  func(ScheduledTask)
+}
+
+func newScheduler(submit func(ScheduledTask)) *scheduler {
+	scheduler := &scheduler{
+		entries: make(map[TaskID]*scheduledEntry),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		submit:  submit,
+	}
+	go scheduler.run()
+	return scheduler
+}
+
+//schedule adds a task due at due and returns its id
+func (scheduler *scheduler) schedule(poolID string, data interface{}, due time.Time) TaskID {
+	scheduler.mutex.Lock()
+	scheduler.lastID++
+	entry := &scheduledEntry{ScheduledTask: ScheduledTask{ID: scheduler.lastID, PoolID: poolID, Data: data, Due: due}}
+	heap.Push(&scheduler.timers, entry)
+	scheduler.entries[entry.ID] = entry
+	soonest := entry.index == 0
+	scheduler.mutex.Unlock()
+	if soonest {
+		select {
+		case scheduler.wake <- struct{}{}:
+		default:
+		}
+	}
+	return entry.ID
+}
+
+//cancel removes the task with id, it returns false if the task is not scheduled anymore
+func (scheduler *scheduler) cancel(id TaskID) bool {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	entry, ok := scheduler.entries[id]
+	if !ok {
+		return false
+	}
+	heap.Remove(&scheduler.timers, entry.index)
+	delete(scheduler.entries, id)
+	return true
+}
+
+//list returns the tasks scheduled for poolID sorted by due time, every scheduled task if poolID is empty
+func (scheduler *scheduler) list(poolID string) []ScheduledTask {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	tasks := make([]ScheduledTask, 0, len(scheduler.entries))
+	for _, entry := range scheduler.entries {
+		if poolID == "" || entry.PoolID == poolID {
+			tasks = append(tasks, entry.ScheduledTask)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].Due.Equal(tasks[j].Due) {
+			return tasks[i].ID < tasks[j].ID
+		}
+		return tasks[i].Due.Before(tasks[j].Due)
+	})
+	return tasks
+}
+
+//count returns the amount of tasks scheduled for poolID
+func (scheduler *scheduler) count(poolID string) int {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	count := 0
+	for _, entry := range scheduler.entries {
+		if entry.PoolID == poolID {
+			count++
+		}
+	}
+	return count
+}
+
+//due pops the tasks already due and returns how long to wait for the next one, a negative duration if there is none
+func (scheduler *scheduler) due(now time.Time) ([]ScheduledTask, time.Duration) {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	var tasks []ScheduledTask
+	for len(scheduler.timers) > 0 {
+		next := scheduler.timers[0]
+		if next.Due.After(now) {
+			return tasks, next.Due.Sub(now)
+		}
+		heap.Pop(&scheduler.timers)
+		delete(scheduler.entries, next.ID)
+		tasks = append(tasks, next.ScheduledTask)
+	}
+	return tasks, -1
+}
+
+func (scheduler *scheduler) run() {
+	defer close(scheduler.done)
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		tasks, wait := scheduler.due(time.Now())
+		for _, task := range tasks {
+			scheduler.submit(task)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		var fire <-chan time.Time
+		if wait >= 0 {
+			timer.Reset(wait)
+			fire = timer.C
+		}
+		select {
+		case <-fire:
+		case <-scheduler.wake:
+		case <-scheduler.stop:
+			return
+		}
+	}
+}
+
+//close stops the scheduler and returns the tasks that were still scheduled
+func (scheduler *scheduler) close() []ScheduledTask {
+	close(scheduler.stop)
+	<-scheduler.done
+	dropped := scheduler.list("")
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	scheduler.timers = nil
+	scheduler.entries = make(map[TaskID]*scheduledEntry)
+	return dropped
+}
+
+//SubmitAfter schedules data to be submitted to poolID once delay is over and returns the id to cancel it
+func (manager *Manager) SubmitAfter(poolID string, delay time.Duration, data interface{}) (TaskID, error) {
+	return manager.SubmitAt(poolID, time.Now().Add(delay), data)
+}
+
+//SubmitAt schedules data to be submitted to poolID at due and returns the id to cancel it.
+//Once due the task waits for room in the queue of the pool if it is full. If the pool does not accept the task
+//it is counted in the RejectedScheduledTasks of the pool and moved to its dead-letter queue, if it has one
+func (manager *Manager) SubmitAt(poolID string, due time.Time, data interface{}) (TaskID, error) {
+	if data == nil {
+		return 0, errors.New("data cannot be nil")
+	}
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	if manager.shutdown {
+		return 0, ErrShutdown
+	}
+	if _, ok := manager.pools[poolID]; !ok {
+		return 0, errors.New(fmt.Sprintf("No pool exists for poolID: %s", poolID))
+	}
+	if manager.scheduler == nil {
+		manager.scheduler = newScheduler(manager.submitScheduled)
+	}
+	return manager.scheduler.schedule(poolID, data, due), nil
+}
+
+//CancelScheduled cancels a task scheduled through SubmitAfter or SubmitAt before it is submitted
+func (manager *Manager) CancelScheduled(id TaskID) error {
+	scheduler := manager.getScheduler()
+	if scheduler == nil || !scheduler.cancel(id) {
+		return errors.New(fmt.Sprintf("task %d is not scheduled", id))
+	}
+	return nil
+}
+
+//ListScheduled returns the tasks scheduled for poolID sorted by due time, every scheduled task if poolID is empty
+func (manager *Manager) ListScheduled(poolID string) []ScheduledTask {
+	scheduler := manager.getScheduler()
+	if scheduler == nil {
+		return []ScheduledTask{}
+	}
+	return scheduler.list(poolID)
+}
+
+func (manager *Manager) getScheduler() *scheduler {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	return manager.scheduler
+}
+
+//submitScheduled submits a task once due. If the queue of the pool is full the task waits for room in the background,
+//so the tasks due next are not delayed
+func (manager *Manager) submitScheduled(scheduled ScheduledTask) {
+	task := &task{ctx: context.Background(), data: scheduled.Data}
+	room, err := manager.tryEnqueue(scheduled.PoolID, task)
+	if err == ErrQueueFull {
+		go manager.awaitScheduled(scheduled, task, room)
+		return
+	}
+	manager.rejectScheduled(scheduled, task, err)
+}
+
+//awaitScheduled submits a due task once the queue of its pool has room, until the pool does not accept tasks anymore
+func (manager *Manager) awaitScheduled(scheduled ScheduledTask, task *task, room <-chan struct{}) {
+	err := ErrQueueFull
+	for err == ErrQueueFull {
+		<-room
+		room, err = manager.tryEnqueue(scheduled.PoolID, task)
+	}
+	manager.rejectScheduled(scheduled, task, err)
+}
+
+//rejectScheduled counts a due task the pool did not accept because of err and moves it to its dead-letter queue.
+//It does nothing if err is nil
+func (manager *Manager) rejectScheduled(scheduled ScheduledTask, task *task, err error) {
+	if err == nil {
+		return
+	}
+	if _, record, ok := manager.lookup(scheduled.PoolID); ok {
+		record.mutex.Lock()
+		record.rejectedDue++
+		record.mutex.Unlock()
+		task.submittedAt = scheduled.Due
+		record.bury(task, err, FailureRejected)
+	}
+}
@@ -0,0 +1,153 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestManager_SubmitAfter(t *testing.T) {
+	manager := createManagerMock(1)
+	if _, err := manager.SubmitAfter("slowProcessing", time.Millisecond, "task"); err == nil {
+		t.Error("SubmitAfter() must fail for a pool not defined")
+	}
+	manager.AddPool("slowProcessing", 1, 10, false)
+	if _, err := manager.SubmitAfter("slowProcessing", time.Millisecond, nil); err == nil {
+		t.Error("SubmitAfter() must fail for nil data")
+	}
+	results := make(chan Result, 2)
+	manager.SetResultsChannel("slowProcessing", results)
+	manager.SetFunc("slowProcessing", func(interface{}) bool { return true })
+	manager.StartPool("slowProcessing")
+	defer manager.StopPool("slowProcessing")
+
+	start := time.Now()
+	id, err := manager.SubmitAfter("slowProcessing", time.Millisecond*30, "task")
+	if err != nil {
+		t.Fatalf("SubmitAfter() error = %v", err)
+	}
+	scheduled := manager.ListScheduled("slowProcessing")
+	if len(scheduled) != 1 || scheduled[0].ID != id || scheduled[0].Data != "task" {
+		t.Errorf("ListScheduled() = %+v", scheduled)
+	}
+	if stats, _ := manager.PoolStats("slowProcessing"); stats.ScheduledTasks != 1 {
+		t.Errorf("ScheduledTasks = %d, want 1", stats.ScheduledTasks)
+	}
+	<-results
+	if elapsed := time.Since(start); elapsed < time.Millisecond*30 {
+		t.Errorf("scheduled task processed after %v, before its delay", elapsed)
+	}
+	if scheduled := manager.ListScheduled(""); len(scheduled) != 0 {
+		t.Errorf("ListScheduled() once submitted = %+v", scheduled)
+	}
+}
+
+func TestManager_SubmitAt(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 1, 10, false)
+	results := make(chan Result, 3)
+	manager.SetResultsChannel("slowProcessing", results)
+	manager.SetFunc("slowProcessing", func(interface{}) bool { return true })
+	manager.StartPool("slowProcessing")
+	defer manager.StopPool("slowProcessing")
+
+	now := time.Now()
+	manager.SubmitAt("slowProcessing", now.Add(time.Millisecond*40), "late")
+	cancelled, _ := manager.SubmitAt("slowProcessing", now.Add(time.Millisecond*20), "cancelled")
+	manager.SubmitAt("slowProcessing", now.Add(time.Millisecond*10), "early")
+	if err := manager.CancelScheduled(cancelled); err != nil {
+		t.Errorf("CancelScheduled() error = %v", err)
+	}
+	if err := manager.CancelScheduled(cancelled); err == nil {
+		t.Error("CancelScheduled() must fail for a task not scheduled anymore")
+	}
+	if first, second := <-results, <-results; first.Data != "early" || second.Data != "late" {
+		t.Errorf("tasks processed in order %v, %v, want early, late", first.Data, second.Data)
+	}
+	select {
+	case result := <-results:
+		t.Errorf("cancelled task processed: %+v", result)
+	case <-time.After(time.Millisecond * 20):
+	}
+}
+
+func TestManager_SubmitAtRejected(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPoolWithOptions("slowProcessing", PoolOptions{InitialWorkers: 1, MaxJobsInQueue: 10, DeadLetterQueue: "failures"})
+	manager.SetFunc("slowProcessing", func(interface{}) bool { return true })
+	manager.StartPool("slowProcessing")
+	due := time.Now().Add(time.Millisecond * 10)
+	manager.SubmitAt("slowProcessing", due, "task")
+	manager.StopPool("slowProcessing")
+
+	deadline := time.Now().Add(time.Second)
+	letters := manager.ListDeadLetters("failures")
+	for len(letters) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		letters = manager.ListDeadLetters("failures")
+	}
+	if len(letters) != 1 || letters[0].Reason != FailureRejected || !letters[0].SubmittedAt.Equal(due) {
+		t.Errorf("ListDeadLetters() = %+v, want the task rejected by the stopped pool", letters)
+	}
+	if stats, _ := manager.PoolStats("slowProcessing"); stats.RejectedScheduledTasks != 1 {
+		t.Errorf("RejectedScheduledTasks = %d, want 1", stats.RejectedScheduledTasks)
+	}
+}
+
+func TestManager_SubmitAtQueueFull(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 1, 1, false)
+	results := make(chan Result, 3)
+	manager.SetResultsChannel("slowProcessing", results)
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	manager.SetFunc("slowProcessing", func(interface{}) bool {
+		started <- struct{}{}
+		<-release
+		return true
+	})
+	manager.StartPool("slowProcessing")
+	defer manager.StopPool("slowProcessing")
+	manager.TrySubmit("slowProcessing", "first")
+	<-started
+	manager.TrySubmit("slowProcessing", "second")
+
+	manager.SubmitAfter("slowProcessing", time.Millisecond, "scheduled")
+	// the task is due while the queue is full, it waits for room instead of being rejected
+	if !waitFor(func() bool { return len(manager.ListScheduled("")) == 0 }) {
+		t.Fatal("the scheduled task is not due")
+	}
+	time.Sleep(time.Millisecond * 10)
+	if stats, _ := manager.PoolStats("slowProcessing"); stats.RejectedScheduledTasks != 0 {
+		t.Errorf("RejectedScheduledTasks = %d while the queue is full, want 0", stats.RejectedScheduledTasks)
+	}
+	close(release)
+	processed := map[interface{}]bool{}
+	for i := 0; i < 3; i++ {
+		select {
+		case result := <-results:
+			processed[result.Data] = true
+		case <-time.After(time.Second):
+			t.Fatalf("tasks processed = %v, want the scheduled one once the queue has room", processed)
+		}
+	}
+	if !processed["scheduled"] {
+		t.Errorf("tasks processed = %v, want the scheduled one", processed)
+	}
+}
+
+func TestManager_ShutdownDropsScheduledTasks(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 1, 10, false)
+	manager.SubmitAfter("slowProcessing", time.Hour, "task")
+	report, err := manager.Shutdown(context.Background())
+	if err != nil || len(report.Pools) != 1 || report.Pools[0].Unscheduled != 1 {
+		t.Errorf("Shutdown() = %+v, %v, want a dropped scheduled task", report, err)
+	}
+	if _, err := manager.SubmitAfter("slowProcessing", time.Millisecond, "task"); err != ErrShutdown {
+		t.Errorf("SubmitAfter() after Shutdown() error = %v, want %v", err, ErrShutdown)
+	}
+	if scheduled := manager.ListScheduled(""); len(scheduled) != 0 {
+		t.Errorf("ListScheduled() after Shutdown() = %+v", scheduled)
+	}
+}
@@ -0,0 +1,169 @@
+package manager
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"sort"
+	"sync"
+)
+
+//ErrShutdown is returned when an operation is requested to a manager that has been shut down
+var ErrShutdown = errors.New("manager has been shut down")
+
+//PoolShutdownReport tells what happened to the tasks of a pool while the manager was shutting down
+type PoolShutdownReport struct {
+	PoolID string
+	//Completed is the amount of tasks processed, successfully or not, during the shutdown
+	Completed int
+	//Abandoned is the amount of tasks cancelled once a worker picked them up, among them the ones still running when the deadline was reached
+	Abandoned int
+	//Queued is the amount of tasks left in the queue without being processed. The ones cancelled by a forced stop
+	//have their futures resolved with context.Canceled
+	Queued int
+	//Forced tells whether the pool had to be stopped before being drained
+	Forced bool
+	//Unscheduled is the amount of tasks scheduled for later that were dropped
+	Unscheduled int
+}
+
+//ShutdownReport gathers the report of every pool, sorted by pool id
+type ShutdownReport struct {
+	Pools []PoolShutdownReport
+}
+
+//taskCounters is a snapshot of the task counters of a pool
+type taskCounters struct {
+	processed int
+	cancelled int
+	running   int
+	pending   int
+}
+
+func (record *poolRecord) counters() taskCounters {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	return taskCounters{
+		processed: record.completed + record.failed,
+		cancelled: record.cancelled,
+		running:   record.running,
+		pending:   record.pending,
+	}
+}
+
+//Shutdown stops accepting new tasks and pools, lets every pool process its queued and in-flight tasks and stops them.
+//Once ctx is done the pools still draining are force-stopped: the context of their tasks is cancelled and their
+//workers are terminated without waiting anymore; in that case the error of ctx is returned along with the report.
+//Pools with queued tasks and no workers to process them are force-stopped right away, ErrNoWorkers is returned then if ctx is not done.
+//Tasks scheduled for later are dropped, recurring jobs are removed and pools are not autoscaled anymore
+func (manager *Manager) Shutdown(ctx context.Context) (ShutdownReport, error) {
+	manager.mutex.Lock()
+	if manager.shutdown {
+		manager.mutex.Unlock()
+		return ShutdownReport{}, ErrShutdown
+	}
+	manager.shutdown = true
+	scheduler := manager.scheduler
+	jobs := manager.jobs
+	manager.jobs = nil
+	manager.mutex.Unlock()
+	for _, job := range jobs {
+		job.close()
+	}
+	unscheduled := map[string]int{}
+	if scheduler != nil {
+		for _, task := range scheduler.close() {
+			unscheduled[task.PoolID]++
+		}
+	}
+
+	poolIDs := manager.ListPools()
+	report := ShutdownReport{Pools: make([]PoolShutdownReport, len(poolIDs))}
+	waitGroup := new(sync.WaitGroup)
+	waitGroup.Add(len(poolIDs))
+	for i, poolID := range poolIDs {
+		go func(i int, poolID string) {
+			defer waitGroup.Done()
+			report.Pools[i] = manager.shutdownPool(ctx, poolID)
+			report.Pools[i].Unscheduled = unscheduled[poolID]
+		}(i, poolID)
+	}
+	waitGroup.Wait()
+	sort.Slice(report.Pools, func(i, j int) bool {
+		return report.Pools[i].PoolID < report.Pools[j].PoolID
+	})
+	for _, poolReport := range report.Pools {
+		if poolReport.Forced && ctx.Err() == nil {
+			return report, ErrNoWorkers
+		}
+		if poolReport.Forced {
+			return report, ctx.Err()
+		}
+	}
+	return report, nil
+}
+
+//shutdownPool drains poolID until ctx is done and force-stops it afterwards
+func (manager *Manager) shutdownPool(ctx context.Context, poolID string) PoolShutdownReport {
+	poolReport := PoolShutdownReport{PoolID: poolID}
+	pool, record, ok := manager.lookup(poolID)
+	if !ok {
+		return poolReport
+	}
+	before := record.counters()
+
+	record.mutex.Lock()
+	record.setAutoscaler(manager, pool, nil)
+	record.mutex.Unlock()
+
+	record.mutex.Lock()
+	state := record.state
+	if state == PoolDefined {
+		// a pool never started has no workers to process its queue
+		record.state = PoolStopped
+	}
+	record.mutex.Unlock()
+
+	var err error
+	dropped := 0
+	switch state {
+	case PoolStarted, PoolPaused:
+		err = manager.stopPool(ctx, poolID, "shut down", false)
+	case PoolDraining:
+		select {
+		case <-record.idleChan():
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	if err == ErrNoWorkers || err != nil && ctx.Err() != nil {
+		poolReport.Forced = true
+		record.resizing.Lock()
+		record.mutex.Lock()
+		record.cancel()
+		record.state = PoolStopped
+		record.vacate()
+		record.mutex.Unlock()
+		pool.EditWorkersAmount(0)
+		manager.workerBudget().request(poolID, 0, true)
+		record.resizing.Unlock()
+		dropped = record.cancelQueued(pool, context.Canceled)
+	}
+
+	if record.durable != nil {
+		record.durable.close()
+	}
+	after := record.counters()
+	poolReport.Completed = after.processed - before.processed
+	poolReport.Queued = after.pending - after.running + dropped
+	poolReport.Abandoned = after.cancelled - before.cancelled - dropped
+	if poolReport.Forced {
+		poolReport.Abandoned += after.running
+	}
+	return poolReport
+}
+
+func (manager *Manager) isShutdown() bool {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	return manager.shutdown
+}
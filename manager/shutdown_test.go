@@ -0,0 +1,96 @@
+package manager
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"testing"
+	"time"
+)
+
+func TestManager_Shutdown(t *testing.T) {
+	manager := createManagerMock(2)
+	manager.AddPool("slowProcessing", 2, 10, false)
+	manager.AddPool("fastProcessing", 1, 10, false)
+	manager.SetFunc("slowProcessing", func(interface{}) bool {
+		time.Sleep(time.Millisecond * 10)
+		return true
+	})
+	manager.StartPool("slowProcessing")
+	for i := 0; i < 5; i++ {
+		manager.AddTaskToPool("slowProcessing", i)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	report, err := manager.Shutdown(ctx)
+	if err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	want := []PoolShutdownReport{
+		{PoolID: "fastProcessing"},
+		{PoolID: "slowProcessing", Completed: 5},
+	}
+	if len(report.Pools) != len(want) || report.Pools[0] != want[0] || report.Pools[1] != want[1] {
+		t.Errorf("Shutdown() report = %+v, want %+v", report.Pools, want)
+	}
+	for _, poolID := range []string{"slowProcessing", "fastProcessing"} {
+		if stats, _ := manager.PoolStats(poolID); stats.State != PoolStopped {
+			t.Errorf("pool %s state = %v, want %v", poolID, stats.State, PoolStopped)
+		}
+	}
+
+	if _, ok := errors.Cause(manager.AddTaskToPool("slowProcessing", "late")).(*StateError); !ok {
+		t.Error("AddTaskToPool() after Shutdown() must return a StateError")
+	}
+	if err := manager.AddPool("newProcessing", 1, 1, false); err != ErrShutdown {
+		t.Errorf("AddPool() after Shutdown() error = %v, want %v", err, ErrShutdown)
+	}
+	if err := manager.RestartPool("slowProcessing"); err != ErrShutdown {
+		t.Errorf("RestartPool() after Shutdown() error = %v, want %v", err, ErrShutdown)
+	}
+	if _, err := manager.Shutdown(ctx); err != ErrShutdown {
+		t.Errorf("second Shutdown() error = %v, want %v", err, ErrShutdown)
+	}
+}
+
+func TestManager_ShutdownDeadline(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 1, 10, false)
+	started := make(chan struct{}, 1)
+	manager.SetFuncContext("slowProcessing", func(ctx context.Context, data interface{}) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	manager.StartPool("slowProcessing")
+	futures := make([]*Future, 0, 4)
+	for i := 0; i < 4; i++ {
+		future, _ := manager.SubmitWithResult("slowProcessing", i)
+		futures = append(futures, future)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	report, err := manager.Shutdown(ctx)
+	if errors.Cause(err) != context.DeadlineExceeded {
+		t.Errorf("Shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if len(report.Pools) != 1 {
+		t.Fatalf("Shutdown() report = %+v", report)
+	}
+	poolReport := report.Pools[0]
+	if !poolReport.Forced || poolReport.Completed != 0 || poolReport.Abandoned < 1 || poolReport.Abandoned+poolReport.Queued != 4 {
+		t.Errorf("Shutdown() report = %+v, want 4 tasks abandoned or queued", poolReport)
+	}
+	for i, future := range futures {
+		select {
+		case <-future.Done():
+			if _, err := future.Await(context.Background()); err != context.Canceled {
+				t.Errorf("future of task %d error = %v, want context.Canceled", i, err)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("future of task %d is not resolved after a forced shutdown", i)
+		}
+	}
+}
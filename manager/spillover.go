@@ -0,0 +1,114 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/ericbrisrubio/go-workers-multipool/pool"
+	"github.com/pkg/errors"
+	"time"
+)
+
+//SpilloverPolicy makes the tasks submitted to a saturated pool overflow to fallback pools
+type SpilloverPolicy struct {
+	//Fallbacks are the pools tried in order when the pool is saturated, the first one not saturated gets the task.
+	//If every fallback is saturated the task is submitted to the pool anyway
+	Fallbacks []string
+	//QueueDepth is the amount of queued tasks from which the pool is saturated, 0 means once its queue is full
+	QueueDepth int
+	//MaxWait saturates the pool once a new task is expected to wait longer than MaxWait before a worker picks it up,
+	//as estimated from the average run time of its tasks. 0 means the wait is not taken into account
+	MaxWait time.Duration
+}
+
+func (policy *SpilloverPolicy) validate(poolID string) error {
+	if len(policy.Fallbacks) == 0 {
+		return errors.New("spillover needs at least a fallback pool")
+	}
+	for _, fallback := range policy.Fallbacks {
+		if fallback == poolID {
+			return errors.New(fmt.Sprintf("pool `%s` cannot spill over to itself", poolID))
+		}
+	}
+	if policy.QueueDepth < 0 {
+		return errors.New("spillover QueueDepth has to be greater or equal to 0")
+	}
+	if policy.MaxWait < 0 {
+		return errors.New("spillover MaxWait has to be greater or equal to 0")
+	}
+	return nil
+}
+
+//SetSpillover defines the spillover policy of poolID, nil disables the spillover
+func (manager *Manager) SetSpillover(poolID string, policy *SpilloverPolicy) error {
+	_, record, ok := manager.lookup(poolID)
+	if !ok {
+		return errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
+	}
+	if policy != nil {
+		if err := policy.validate(poolID); err != nil {
+			return err
+		}
+	}
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.spillover = policy
+	return nil
+}
+
+//spill returns the pool a task submitted to poolID has to be enqueued to, following the spillover policy of poolID.
+//The record of poolID is returned last if the task overflows to a fallback, so the spill is counted once the fallback accepts it
+func (manager *Manager) spill(poolID string, descriptor pool.Descriptor, record *poolRecord) (pool.Descriptor, *poolRecord, *poolRecord) {
+	record.mutex.Lock()
+	policy := record.spillover
+	record.mutex.Unlock()
+	if policy == nil || !record.saturated(policy, descriptor.GetTotalWorkers()) {
+		return descriptor, record, nil
+	}
+	for _, fallbackID := range policy.Fallbacks {
+		fallback, fallbackRecord, ok := manager.lookup(fallbackID)
+		if !ok || fallbackRecord.checkState("add tasks to", PoolDefined, PoolStarted, PoolPaused) != nil {
+			continue
+		}
+		if fallbackRecord.saturated(nil, fallback.GetTotalWorkers()) {
+			continue
+		}
+		return fallback, fallbackRecord, record
+	}
+	return descriptor, record, nil
+}
+
+//saturated tells whether the pool is saturated according to policy, a nil policy saturates the pool once its queue is full
+func (record *poolRecord) saturated(policy *SpilloverPolicy, workers int) bool {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	queued := record.pending - record.running
+	depth := record.capacity
+	if policy != nil && policy.QueueDepth > 0 {
+		depth = policy.QueueDepth
+	}
+	if depth > 0 && queued >= depth {
+		return true
+	}
+	if policy == nil || policy.MaxWait == 0 || workers == 0 {
+		return false
+	}
+	expectedWait := record.runTime * time.Duration(queued+1) / time.Duration(workers)
+	return expectedWait > policy.MaxWait
+}
+
+//observe updates the average run time of the tasks of the pool with the one of a task that took runTime
+func (record *poolRecord) observe(runTime time.Duration) {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	if record.runTime == 0 {
+		record.runTime = runTime
+		return
+	}
+	record.runTime += (runTime - record.runTime) / 5
+}
+
+//countSpill counts a task that overflowed to a fallback pool
+func (record *poolRecord) countSpill() {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.spilled++
+}
@@ -0,0 +1,164 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestManager_SetSpillover(t *testing.T) {
+	manager := createManagerMock(2)
+	manager.AddPool("big-size", 1, 10, false)
+	manager.AddPool("low-size", 1, 10, false)
+	type args struct {
+		poolID string
+		policy *SpilloverPolicy
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			"Returns error if pool with {poolId} id does not exist",
+			args{"fastProcessing", &SpilloverPolicy{Fallbacks: []string{"low-size"}}},
+			true,
+		},
+		{
+			"Returns error if there is no fallback",
+			args{"big-size", &SpilloverPolicy{}},
+			true,
+		},
+		{
+			"Returns error if the pool spills over to itself",
+			args{"big-size", &SpilloverPolicy{Fallbacks: []string{"big-size"}}},
+			true,
+		},
+		{
+			"Returns error if the queue depth is negative",
+			args{"big-size", &SpilloverPolicy{Fallbacks: []string{"low-size"}, QueueDepth: -1}},
+			true,
+		},
+		{
+			"Sets the spillover of an existing pool",
+			args{"big-size", &SpilloverPolicy{Fallbacks: []string{"low-size"}, QueueDepth: 2}},
+			false,
+		},
+		{
+			"Disables the spillover of an existing pool",
+			args{"big-size", nil},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := manager.SetSpillover(tt.args.poolID, tt.args.policy); (err != nil) != tt.wantErr {
+				t.Errorf("SetSpillover() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManager_SpilloverQueueDepth(t *testing.T) {
+	manager := createManagerMock(3)
+	manager.AddPoolWithOptions("big-size", PoolOptions{
+		InitialWorkers: 1,
+		MaxJobsInQueue: 10,
+		Spillover:      &SpilloverPolicy{Fallbacks: []string{"stopped", "overflow"}, QueueDepth: 2},
+	})
+	manager.AddPool("stopped", 1, 10, false)
+	manager.AddPool("overflow", 1, 10, false)
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	manager.SetFunc("big-size", func(interface{}) bool {
+		started <- struct{}{}
+		<-release
+		return true
+	})
+	manager.SetFunc("stopped", func(interface{}) bool { return true })
+	manager.SetFunc("overflow", func(interface{}) bool { return true })
+	for _, poolID := range []string{"big-size", "stopped", "overflow"} {
+		manager.StartPool(poolID)
+	}
+	manager.StopPool("stopped")
+
+	manager.AddTaskToPool("big-size", "first")
+	<-started
+	manager.AddTaskToPool("big-size", "second")
+	manager.AddTaskToPool("big-size", "third")
+	manager.AddTaskToPool("big-size", "spilled")
+	go func() {
+		for range started {
+		}
+	}()
+	close(release)
+	manager.StopPool("big-size")
+	manager.StopPool("overflow")
+	close(started)
+
+	bigSize, _ := manager.PoolStats("big-size")
+	overflow, _ := manager.PoolStats("overflow")
+	if bigSize.CompletedTasks != 3 || bigSize.SpilledTasks != 1 || overflow.CompletedTasks != 1 {
+		t.Errorf("expected a task to spill over, got %+v and %+v", bigSize, overflow)
+	}
+}
+
+func TestManager_SpilloverMaxWait(t *testing.T) {
+	manager := createManagerMock(2)
+	manager.AddPoolWithOptions("big-size", PoolOptions{
+		InitialWorkers: 1,
+		MaxJobsInQueue: 10,
+		Spillover:      &SpilloverPolicy{Fallbacks: []string{"overflow"}, MaxWait: time.Millisecond * 10},
+	})
+	manager.AddPool("overflow", 1, 10, false)
+	results := make(chan Result, 2)
+	for _, poolID := range []string{"big-size", "overflow"} {
+		manager.SetResultsChannel(poolID, results)
+		manager.SetFunc(poolID, func(interface{}) bool {
+			time.Sleep(time.Millisecond * 30)
+			return true
+		})
+		manager.StartPool(poolID)
+	}
+
+	future, _ := manager.SubmitWithResult("big-size", "first")
+	future.Await(context.Background())
+	if result := <-results; result.PoolID != "big-size" {
+		t.Errorf("first task processed by %s, want big-size", result.PoolID)
+	}
+	manager.AddTaskToPool("big-size", "second")
+	if result := <-results; result.PoolID != "overflow" {
+		t.Errorf("task expected to wait longer than MaxWait processed by %s, want overflow", result.PoolID)
+	}
+	manager.StopPool("big-size")
+	manager.StopPool("overflow")
+}
+
+func TestManager_SpilloverRejectedByFallback(t *testing.T) {
+	manager := createManagerMock(2)
+	manager.AddPoolWithOptions("big-size", PoolOptions{
+		InitialWorkers: 1,
+		MaxJobsInQueue: 10,
+		Spillover:      &SpilloverPolicy{Fallbacks: []string{"overflow"}, QueueDepth: 1},
+	})
+	// without worker function the fallback fails to enqueue the tasks it is handed
+	manager.AddPool("overflow", 1, 10, false)
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{}, 1)
+	manager.SetFunc("big-size", func(interface{}) bool {
+		started <- struct{}{}
+		<-release
+		return true
+	})
+	manager.StartPool("big-size")
+	manager.AddTaskToPool("big-size", "image-1")
+	<-started
+	manager.AddTaskToPool("big-size", "image-2")
+	if err := manager.AddTaskToPool("big-size", "image-3"); err == nil {
+		t.Error("AddTaskToPool() must fail once the fallback rejects the task")
+	}
+	if stats, _ := manager.PoolStats("big-size"); stats.SpilledTasks != 0 {
+		t.Errorf("SpilledTasks = %d, want 0 as the fallback did not accept the task", stats.SpilledTasks)
+	}
+}
@@ -0,0 +1,96 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+	"time"
+)
+
+//PoolStats is a snapshot of the workers and tasks of a pool
+type PoolStats struct {
+	PoolID         string
+	State          PoolState
+	TotalWorkers   int
+	ActiveWorkers  int
+	IdleWorkers    int
+	QueuedTasks    int
+	InFlightTasks  int
+	CompletedTasks int
+	FailedTasks    int
+	CancelledTasks int
+	RetriedTasks   int
+	PanickedTasks  int
+	TimedOutTasks  int
+	//AbandonedHandlers is the amount of worker functions of timed out tasks still running in the background
+	AbandonedHandlers int
+	SpilledTasks      int
+	ScheduledTasks    int
+	//RejectedScheduledTasks is the amount of scheduled tasks the pool did not accept once due, they are moved to
+	//its dead-letter queue if it has one
+	RejectedScheduledTasks int
+	//PersistedTasks is the amount of tasks in the durable queue of the pool not acked yet
+	PersistedTasks int
+	//ActiveKeys is the amount of keys with tasks queued or in flight, see SubmitKeyed
+	ActiveKeys int
+	//HotKeys are the keys with the most tasks queued or in flight, nil for pools without keyed tasks
+	HotKeys []KeyStats
+	//ThrottledTime is the time the tasks of the pool spent waiting for the rate limit
+	ThrottledTime time.Duration
+	//QueuedByPriority is the amount of queued tasks by priority level, nil for pools without priorities
+	QueuedByPriority map[int]int
+}
+
+//ListPools returns the ids of the registered pools sorted alphabetically
+func (manager *Manager) ListPools() []string {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	poolIDs := make([]string, 0, len(manager.pools))
+	for poolID := range manager.pools {
+		poolIDs = append(poolIDs, poolID)
+	}
+	sort.Strings(poolIDs)
+	return poolIDs
+}
+
+//PoolStats returns how busy poolID is along with its lifecycle state
+func (manager *Manager) PoolStats(poolID string) (PoolStats, error) {
+	pool, record, ok := manager.lookup(poolID)
+	if !ok {
+		return PoolStats{}, errors.New(fmt.Sprintf("pool with %s id is not defined", poolID))
+	}
+	stats := PoolStats{
+		PoolID:        poolID,
+		TotalWorkers:  pool.GetTotalWorkers(),
+		ActiveWorkers: pool.GetTotalWorkersInProgress(),
+		QueuedTasks:   pool.GetQueuedTasks(),
+	}
+	if stats.IdleWorkers = stats.TotalWorkers - stats.ActiveWorkers; stats.IdleWorkers < 0 {
+		stats.IdleWorkers = 0
+	}
+	if scheduler := manager.getScheduler(); scheduler != nil {
+		stats.ScheduledTasks = scheduler.count(poolID)
+	}
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	stats.State = record.state
+	stats.InFlightTasks = record.running
+	stats.CompletedTasks = record.completed
+	stats.FailedTasks = record.failed
+	stats.CancelledTasks = record.cancelled
+	stats.RetriedTasks = record.retried
+	stats.PanickedTasks = record.panicked
+	stats.TimedOutTasks = record.timedOut
+	stats.AbandonedHandlers = record.abandoned
+	stats.SpilledTasks = record.spilled
+	stats.RejectedScheduledTasks = record.rejectedDue
+	stats.ThrottledTime = record.throttled
+	stats.ActiveKeys, stats.HotKeys = record.keys.stats()
+	if record.durable != nil {
+		stats.PersistedTasks = record.durable.log.Len()
+	}
+	if record.priority != nil {
+		stats.QueuedByPriority = record.priority.depths()
+	}
+	return stats, nil
+}
@@ -0,0 +1,101 @@
+package manager
+
+import (
+	"github.com/ericbrisrubio/go-workers-multipool/pool"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestManager_ListPools(t *testing.T) {
+	tests := []struct {
+		name    string
+		poolIDs []string
+		want    []string
+	}{
+		{
+			"Returns an empty list if no pool is declared",
+			[]string{},
+			[]string{},
+		},
+		{
+			"Returns the pool ids sorted",
+			[]string{"slowProcessing", "fastProcessing", "mediumProcessing"},
+			[]string{"fastProcessing", "mediumProcessing", "slowProcessing"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := createManagerMock(len(tt.poolIDs))
+			for _, poolID := range tt.poolIDs {
+				manager.AddPool(poolID, 1, 1, false)
+			}
+			if got := manager.ListPools(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListPools() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_PoolStats(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 2, 2, false)
+	poolMock := &pool.GoWorkerPoolFake{}
+	manager.pools["slowProcessing"] = poolMock
+	manager.SetFunc("slowProcessing", func(data interface{}) bool {
+		return data.(bool)
+	})
+	manager.StartPool("slowProcessing")
+	manager.AddTaskToPool("slowProcessing", true)
+	manager.AddTaskToPool("slowProcessing", true)
+	manager.AddTaskToPool("slowProcessing", false)
+
+	if _, err := manager.PoolStats("fastProcessing"); err == nil {
+		t.Error("PoolStats() must fail for a pool not defined")
+	}
+	got, err := manager.PoolStats("slowProcessing")
+	if err != nil {
+		t.Fatalf("PoolStats() error = %v", err)
+	}
+	want := PoolStats{
+		PoolID:         "slowProcessing",
+		State:          PoolStarted,
+		TotalWorkers:   2,
+		IdleWorkers:    2,
+		CompletedTasks: 2,
+		FailedTasks:    1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PoolStats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestManager_PoolStatsWhileProcessing(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 1, 10, false)
+	release := make(chan struct{})
+	manager.SetFunc("slowProcessing", func(data interface{}) bool {
+		<-release
+		return true
+	})
+	manager.StartPool("slowProcessing")
+	manager.AddTaskToPool("slowProcessing", "first")
+	manager.AddTaskToPool("slowProcessing", "second")
+
+	deadline := time.Now().Add(time.Second)
+	stats, _ := manager.PoolStats("slowProcessing")
+	for (stats.InFlightTasks != 1 || stats.QueuedTasks != 1) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		stats, _ = manager.PoolStats("slowProcessing")
+	}
+	if stats.InFlightTasks != 1 || stats.QueuedTasks != 1 || stats.TotalWorkers != 1 {
+		t.Errorf("PoolStats() while processing = %+v", stats)
+	}
+
+	close(release)
+	manager.StopPool("slowProcessing")
+	stats, _ = manager.PoolStats("slowProcessing")
+	if stats.CompletedTasks != 2 || stats.InFlightTasks != 0 || stats.QueuedTasks != 0 || stats.State != PoolStopped {
+		t.Errorf("PoolStats() after stopping = %+v", stats)
+	}
+}
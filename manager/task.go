@@ -0,0 +1,151 @@
+package manager
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"sync/atomic"
+	"time"
+)
+
+//ErrTaskFailed is the error reported for a task whose worker function returned false
+var ErrTaskFailed = errors.New("task has not been successfully processed")
+
+//taskAdder is the part of a pool the tasks are handed to
+type taskAdder interface {
+	AddTask(data interface{}) error
+}
+
+//workerPool is the part of a pool its worker function needs
+type workerPool interface {
+	taskAdder
+	GetTotalWorkers() int
+}
+
+//handler is the function run by the workers of a pool for every task
+type handler func(ctx context.Context, data interface{}) (interface{}, error)
+
+//errorHandler adapts a worker function returning only an error to a handler
+func errorHandler(workerFunc func(context.Context, interface{}) error) handler {
+	return func(ctx context.Context, data interface{}) (interface{}, error) {
+		return nil, workerFunc(ctx, data)
+	}
+}
+
+//taskOutcome is how the processing of a task ended
+type taskOutcome int
+
+const (
+	outcomeCompleted taskOutcome = iota
+	outcomeFailed
+	outcomeCancelled
+)
+
+//task wraps the data submitted to a pool so the manager can keep track of it until it is processed
+type task struct {
+	ctx         context.Context
+	poolCtx     context.Context
+	data        interface{}
+	future      *Future
+	attempt     int
+	priority    int
+	key         string
+	durableID   uint64
+	timeout     time.Duration
+	submittedAt time.Time
+	discarded   int32
+}
+
+//discard flags a task the pool could not enqueue, so it is skipped if the pool still hands it to a worker
+func (task *task) discard() {
+	atomic.StoreInt32(&task.discarded, 1)
+}
+
+func (task *task) isDiscarded() bool {
+	return atomic.LoadInt32(&task.discarded) == 1
+}
+
+//context returns the context the task runs with, done as soon as the context given on submission
+//or the context of the pool run the task was submitted to is done
+func (task *task) context() (context.Context, context.CancelFunc) {
+	if task.ctx.Done() == nil {
+		return context.WithCancel(task.poolCtx)
+	}
+	ctx, cancel := context.WithCancel(task.ctx)
+	go func() {
+		select {
+		case <-task.poolCtx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+//boolHandler adapts a worker function returning the success of the task to a handler, the value of the task is that success
+func boolHandler(workerFunc func(interface{}) bool) handler {
+	return func(ctx context.Context, data interface{}) (interface{}, error) {
+		if !workerFunc(data) {
+			return false, ErrTaskFailed
+		}
+		return true, nil
+	}
+}
+
+//execute builds the function given to the pool workers: it unwraps each task, runs handle
+//with its data, keeps the task counters of the pool and delivers the result of the task.
+//Tasks whose context is done before a worker picks them up are skipped and panics of handle are recovered as failures.
+//Failed tasks allowed by the retry policy of the pool are added again to pool once their backoff is over
+func (record *poolRecord) execute(pool workerPool, handle handler) func(interface{}) bool {
+	return func(data interface{}) bool {
+		task, ok := record.unwrap(data)
+		if !ok {
+			_, err := handle.run(context.Background(), data)
+			return err == nil
+		}
+		if task == nil || task.isDiscarded() {
+			return false
+		}
+		record.awaitAbandoned(task, pool.GetTotalWorkers())
+		if !record.pickUp(task) {
+			return false
+		}
+		ctx, cancel := task.context()
+		defer cancel()
+		var value interface{}
+		err := ctx.Err()
+		if err == nil {
+			err = record.throttle(ctx)
+		}
+		if err == nil {
+			startedAt := time.Now()
+			value, err = record.invoke(ctx, handle, task)
+			record.observe(time.Since(startedAt))
+		}
+		_, panicked := err.(*PanicError)
+		if panicked {
+			record.countPanic()
+		}
+		if policy := record.policy(); err != nil && ctx.Err() == nil && policy.retries(task.attempt, err) {
+			record.postpone()
+			go record.retry(pool, task, policy.delay(task.attempt))
+			return false
+		}
+		record.deliver(task, value, err)
+		outcome := outcomeFailed
+		switch {
+		case err == nil:
+			outcome = outcomeCompleted
+		case ctx.Err() != nil:
+			outcome = outcomeCancelled
+		case panicked:
+			record.bury(task, err, FailurePanic)
+		case err == ErrTaskTimeout:
+			record.bury(task, err, FailureTimeout)
+		default:
+			record.bury(task, err, FailureError)
+		}
+		record.finish(outcome)
+		record.conclude(pool, task, outcome)
+		return err == nil
+	}
+}
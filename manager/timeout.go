@@ -0,0 +1,124 @@
+package manager
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"sync/atomic"
+	"time"
+)
+
+//ErrTaskTimeout is the error of a task whose worker function did not return within the timeout of the task
+var ErrTaskTimeout = errors.New("task execution timed out")
+
+type timeoutKey struct{}
+
+//states of a worker function run with a timeout
+const (
+	handlerRunning int32 = iota
+	handlerReturned
+	handlerAbandoned
+)
+
+//WithTaskTimeout returns a copy of ctx making the task submitted with it time out once its worker function
+//has been running for timeout, overriding the default timeout of the pool
+func WithTaskTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, timeout)
+}
+
+//taskTimeout returns the timeout set on ctx through WithTaskTimeout, 0 if none
+func taskTimeout(ctx context.Context) time.Duration {
+	timeout, _ := ctx.Value(timeoutKey{}).(time.Duration)
+	return timeout
+}
+
+//invoke runs handle with the data of task bounded by its timeout, or by the default timeout of the pool.
+//Once the timeout is over the context given to handle is done and the worker stops waiting for it, returning ErrTaskTimeout.
+//A worker function ignoring its context keeps running in the background but does not hold the worker anymore,
+//it is counted as abandoned until it returns
+func (record *poolRecord) invoke(ctx context.Context, handle handler, task *task) (interface{}, error) {
+	ctx = context.WithValue(ctx, attemptKey{}, task.attempt)
+	timeout := task.timeout
+	if timeout == 0 {
+		record.mutex.Lock()
+		timeout = record.timeout
+		record.mutex.Unlock()
+	}
+	if timeout <= 0 {
+		return handle.run(ctx, task.data)
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	type outcome struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan outcome, 1)
+	// whichever of the handler returning and the worker giving up on it comes first settles the state
+	state := handlerRunning
+	go func() {
+		value, err := handle.run(runCtx, task.data)
+		if !atomic.CompareAndSwapInt32(&state, handlerRunning, handlerReturned) {
+			record.settleAbandoned()
+		}
+		done <- outcome{value, err}
+	}()
+	select {
+	case result := <-done:
+		if result.err == nil || runCtx.Err() != context.DeadlineExceeded || ctx.Err() != nil {
+			return result.value, result.err
+		}
+	case <-runCtx.Done():
+		if ctx.Err() != nil {
+			result := <-done
+			return result.value, result.err
+		}
+	}
+	record.countTimeout()
+	if atomic.CompareAndSwapInt32(&state, handlerRunning, handlerAbandoned) {
+		record.abandonHandler()
+	}
+	return nil, ErrTaskTimeout
+}
+
+//countTimeout counts a task whose worker function timed out
+func (record *poolRecord) countTimeout() {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.timedOut++
+}
+
+//abandonHandler counts a worker function left running by a timed out task
+func (record *poolRecord) abandonHandler() {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.abandoned++
+}
+
+//settleAbandoned uncounts an abandoned worker function once it returns and wakes up the workers waiting for it
+func (record *poolRecord) settleAbandoned() {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.abandoned--
+	close(record.returned)
+	record.returned = make(chan struct{})
+}
+
+//awaitAbandoned holds the worker picking up task while the abandoned worker functions are more than workers,
+//so they do not pile up without bound. It stops waiting once the context of task or of its pool run is done
+func (record *poolRecord) awaitAbandoned(task *task, workers int) {
+	for {
+		record.mutex.Lock()
+		abandoned, returned := record.abandoned, record.returned
+		record.mutex.Unlock()
+		if abandoned <= workers {
+			return
+		}
+		select {
+		case <-returned:
+		case <-task.ctx.Done():
+			return
+		case <-task.poolCtx.Done():
+			return
+		}
+	}
+}
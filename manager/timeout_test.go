@@ -0,0 +1,155 @@
+package manager
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestManager_PoolTimeout(t *testing.T) {
+	manager := createManagerMock(1)
+	if err := manager.AddPoolWithOptions("slowProcessing", PoolOptions{MaxJobsInQueue: 1, Timeout: -time.Second}); err == nil {
+		t.Error("AddPoolWithOptions() must fail for a negative timeout")
+	}
+	manager.AddPoolWithOptions("slowProcessing", PoolOptions{
+		InitialWorkers:  1,
+		MaxJobsInQueue:  10,
+		Timeout:         time.Millisecond * 20,
+		DeadLetterQueue: "failures",
+	})
+	hung := make(chan struct{})
+	defer close(hung)
+	manager.SetFunc("slowProcessing", func(data interface{}) bool {
+		if data == "hung" {
+			<-hung
+		}
+		return true
+	})
+	manager.StartPool("slowProcessing")
+	future, _ := manager.SubmitWithResult("slowProcessing", "hung")
+	manager.AddTaskToPool("slowProcessing", "image")
+
+	if _, err := future.Await(context.Background()); err != ErrTaskTimeout {
+		t.Errorf("Await() error = %v, want %v", err, ErrTaskTimeout)
+	}
+	manager.StopPool("slowProcessing")
+	stats, _ := manager.PoolStats("slowProcessing")
+	if stats.TimedOutTasks != 1 || stats.FailedTasks != 1 || stats.CompletedTasks != 1 {
+		t.Errorf("expected the hung task to time out without holding the worker, got %+v", stats)
+	}
+	if letters := manager.ListDeadLetters("failures"); len(letters) != 1 || letters[0].Reason != FailureTimeout {
+		t.Errorf("ListDeadLetters() = %+v, want the timed out task", letters)
+	}
+}
+
+func TestManager_TaskTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		poolTimeout time.Duration
+		taskTimeout time.Duration
+		wantErr     error
+	}{
+		{
+			"Times out a task of a pool without timeout",
+			0,
+			time.Millisecond * 10,
+			ErrTaskTimeout,
+		},
+		{
+			"Extends the timeout of the pool for a task",
+			time.Millisecond * 10,
+			time.Second,
+			nil,
+		},
+		{
+			"Applies the timeout of the pool",
+			time.Millisecond * 10,
+			0,
+			ErrTaskTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := createManagerMock(1)
+			manager.AddPoolWithOptions("slowProcessing", PoolOptions{InitialWorkers: 1, MaxJobsInQueue: 10, Timeout: tt.poolTimeout})
+			results := make(chan Result, 1)
+			manager.SetResultsChannel("slowProcessing", results)
+			manager.SetFuncContext("slowProcessing", func(ctx context.Context, data interface{}) error {
+				select {
+				case <-time.After(time.Millisecond * 50):
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+			manager.StartPool("slowProcessing")
+			manager.AddTaskToPoolContext(WithTaskTimeout(context.Background(), tt.taskTimeout), "slowProcessing", "image")
+			if result := <-results; result.Err != tt.wantErr {
+				t.Errorf("task error = %v, want %v", result.Err, tt.wantErr)
+			}
+			manager.StopPool("slowProcessing")
+		})
+	}
+}
+
+func TestManager_RetryTimedOutTask(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPoolWithOptions("slowProcessing", PoolOptions{
+		InitialWorkers: 1,
+		MaxJobsInQueue: 10,
+		Timeout:        time.Millisecond * 10,
+		Retry:          &RetryPolicy{MaxAttempts: 2},
+	})
+	manager.SetFuncContext("slowProcessing", func(ctx context.Context, data interface{}) error {
+		if Attempt(ctx) == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	manager.StartPool("slowProcessing")
+	manager.AddTaskToPool("slowProcessing", "image")
+	manager.StopPool("slowProcessing")
+	if stats, _ := manager.PoolStats("slowProcessing"); stats.CompletedTasks != 1 || stats.TimedOutTasks != 1 || stats.RetriedTasks != 1 {
+		t.Errorf("expected the timed out task to be retried, got %+v", stats)
+	}
+}
+
+func TestManager_AbandonedHandlers(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPoolWithOptions("slowProcessing", PoolOptions{InitialWorkers: 1, MaxJobsInQueue: 10, Timeout: time.Millisecond * 10})
+	hung := make(chan struct{})
+	var started int64
+	manager.SetFunc("slowProcessing", func(data interface{}) bool {
+		atomic.AddInt64(&started, 1)
+		<-hung
+		return true
+	})
+	manager.StartPool("slowProcessing")
+	futures := make([]*Future, 0, 3)
+	for _, image := range []string{"image-1", "image-2", "image-3"} {
+		future, _ := manager.SubmitWithResult("slowProcessing", image)
+		futures = append(futures, future)
+	}
+	futures[0].Await(context.Background())
+	futures[1].Await(context.Background())
+	time.Sleep(time.Millisecond * 30)
+	stats, _ := manager.PoolStats("slowProcessing")
+	if stats.AbandonedHandlers != 2 || atomic.LoadInt64(&started) != 2 {
+		t.Errorf("expected 2 abandoned handlers holding back the third task, got %+v and %d started", stats, started)
+	}
+
+	close(hung)
+	if _, err := futures[2].Await(context.Background()); err != nil {
+		t.Errorf("Await() of the task held back error = %v", err)
+	}
+	manager.StopPool("slowProcessing")
+	deadline := time.Now().Add(time.Second)
+	for stats, _ = manager.PoolStats("slowProcessing"); stats.AbandonedHandlers != 0 && time.Now().Before(deadline); stats, _ = manager.PoolStats("slowProcessing") {
+		time.Sleep(time.Millisecond)
+	}
+	if stats.AbandonedHandlers != 0 || stats.CompletedTasks != 1 {
+		t.Errorf("expected the abandoned handlers to be settled, got %+v", stats)
+	}
+}
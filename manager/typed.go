@@ -0,0 +1,114 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"reflect"
+)
+
+//TypedPool is a handle to submit tasks of type T to a pool whose workers receive them without any type assertion.
+//It is the core of the task submission: the untyped methods of Manager go through a TypedPool[interface{}]
+type TypedPool[T any] struct {
+	manager *Manager
+	poolID  string
+}
+
+//Register makes workerFunc the function run by the workers of poolID and returns a typed handle to submit tasks to it.
+//From then on the pool only accepts tasks of type T: the untyped submissions of data of another type fail right away
+func Register[T any](manager *Manager, poolID string, workerFunc func(context.Context, T) error) (*TypedPool[T], error) {
+	if workerFunc == nil {
+		return nil, errors.New("workerFunc cannot be nil")
+	}
+	err := register(manager, poolID, func(ctx context.Context, data T) (interface{}, error) {
+		return nil, workerFunc(ctx, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TypedPool[T]{manager: manager, poolID: poolID}, nil
+}
+
+//register makes workerFunc the function run by the workers of poolID, restricting the data of its tasks to T.
+//Every function setter of the manager goes through it, the untyped ones with T being interface{}
+func register[T any](manager *Manager, poolID string, workerFunc func(context.Context, T) (interface{}, error)) error {
+	pool, record, ok := manager.lookup(poolID)
+	if !ok {
+		return errors.New(fmt.Sprintf("Pool with `%s` id does not exist", poolID))
+	}
+	dataType := reflect.TypeOf((*T)(nil)).Elem()
+	record.setDataType(dataType)
+	pool.SetWorkerFunc(record.execute(pool, func(ctx context.Context, data interface{}) (interface{}, error) {
+		value, ok := data.(T)
+		if !ok && data != nil {
+			// a task queued before the function of the pool was replaced by one of another type
+			return nil, errors.New(fmt.Sprintf("pool `%s` expects tasks of type %v, got %T", poolID, dataType, data))
+		}
+		return workerFunc(ctx, value)
+	}))
+	return nil
+}
+
+//untyped returns the handle the untyped methods of the manager submit the tasks of poolID through
+func (manager *Manager) untyped(poolID string) *TypedPool[interface{}] {
+	return &TypedPool[interface{}]{manager: manager, poolID: poolID}
+}
+
+//setDataType restricts the data of the tasks accepted by the pool to dataType
+func (record *poolRecord) setDataType(dataType reflect.Type) {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+	record.dataType = dataType
+}
+
+//accepts returns an error if data is not of the type of the tasks of the pool
+func (record *poolRecord) accepts(data interface{}) error {
+	record.mutex.Lock()
+	dataType := record.dataType
+	record.mutex.Unlock()
+	if dataType == nil {
+		return nil
+	}
+	if data == nil {
+		switch dataType.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func:
+			return nil
+		}
+	} else if reflect.TypeOf(data).AssignableTo(dataType) {
+		return nil
+	}
+	return errors.New(fmt.Sprintf("pool `%s` expects tasks of type %v, got %T", record.poolID, dataType, data))
+}
+
+//PoolID returns the id of the pool behind the handle
+func (typedPool *TypedPool[T]) PoolID() string {
+	return typedPool.poolID
+}
+
+//Submit enqueues data as a new task of the pool
+func (typedPool *TypedPool[T]) Submit(data T) error {
+	return typedPool.submit(context.Background(), data, nil)
+}
+
+//SubmitContext enqueues data as a new task of the pool waiting for room in its queue until ctx is done,
+//cancelling ctx cancels the task
+func (typedPool *TypedPool[T]) SubmitContext(ctx context.Context, data T) error {
+	if ctx == nil {
+		return errors.New("ctx cannot be nil")
+	}
+	return typedPool.manager.enqueueWait(ctx, typedPool.poolID, &task{ctx: ctx, data: data})
+}
+
+//SubmitWithResult enqueues data as a new task of the pool and returns a future to await the error of the worker function
+func (typedPool *TypedPool[T]) SubmitWithResult(data T) (*Future, error) {
+	future := newFuture()
+	if err := typedPool.submit(context.Background(), data, future); err != nil {
+		return nil, err
+	}
+	return future, nil
+}
+
+//submit enqueues data as a new task of the pool cancelled along with ctx, future is resolved once it is over
+func (typedPool *TypedPool[T]) submit(ctx context.Context, data T, future *Future) error {
+	return typedPool.manager.enqueue(typedPool.poolID, &task{ctx: ctx, data: data, future: future})
+}
@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"context"
+	"github.com/ericbrisrubio/go-workers-multipool/pool"
+	"github.com/pkg/errors"
+	"testing"
+)
+
+type imageTask struct {
+	path string
+	size int
+}
+
+func TestRegister(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 2, 2, false)
+	type args struct {
+		poolID     string
+		workerFunc func(context.Context, imageTask) error
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			"Returns error if pool with {poolId} id does not exist",
+			args{"fastProcessing", func(context.Context, imageTask) error { return nil }},
+			true,
+		},
+		{
+			"Returns error if the worker function is nil",
+			args{"slowProcessing", nil},
+			true,
+		},
+		{
+			"Registers the function for an existing pool",
+			args{"slowProcessing", func(context.Context, imageTask) error { return nil }},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typedPool, err := Register(manager, tt.args.poolID, tt.args.workerFunc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && typedPool.PoolID() != tt.args.poolID {
+				t.Errorf("PoolID() = %s, want %s", typedPool.PoolID(), tt.args.poolID)
+			}
+		})
+	}
+}
+
+func TestTypedPool_Submit(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 2, 2, false)
+	manager.pools["slowProcessing"] = &pool.GoWorkerPoolFake{}
+	received := make([]imageTask, 0)
+	typedPool, err := Register(manager, "slowProcessing", func(ctx context.Context, task imageTask) error {
+		received = append(received, task)
+		if task.size == 0 {
+			return errors.New("empty image")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	manager.StartPool("slowProcessing")
+
+	typedPool.Submit(imageTask{path: "/tmp/low.png", size: 10})
+	typedPool.Submit(imageTask{path: "/tmp/empty.png"})
+	if err := manager.AddTaskToPool("slowProcessing", "/tmp/untyped.png"); err == nil {
+		t.Error("AddTaskToPool() of data of another type must return an error")
+	}
+
+	if len(received) != 2 || received[0].path != "/tmp/low.png" {
+		t.Errorf("worker function received %v", received)
+	}
+	stats, _ := manager.PoolStats("slowProcessing")
+	if stats.CompletedTasks != 1 || stats.FailedTasks != 1 {
+		t.Errorf("expected 1 completed and 1 failed tasks, got %+v", stats)
+	}
+}
+
+func TestTypedPool_SubmitStoppedPool(t *testing.T) {
+	manager := createManagerMock(1)
+	manager.AddPool("slowProcessing", 2, 2, false)
+	manager.pools["slowProcessing"] = &pool.GoWorkerPoolFake{}
+	typedPool, _ := Register(manager, "slowProcessing", func(ctx context.Context, path string) error { return nil })
+	manager.StartPool("slowProcessing")
+	manager.StopPool("slowProcessing")
+	if _, ok := errors.Cause(typedPool.Submit("/tmp/low.png")).(*StateError); !ok {
+		t.Error("Submit() on a stopped pool must return a StateError")
+	}
+}
@@ -0,0 +1,81 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"github.com/enriquebris/goworkerpool"
+	"sort"
+	"sync"
+)
+
+const (
+	//GoWorkerPoolBackend is the backend built on goworkerpool, used when none is chosen
+	GoWorkerPoolBackend = "goworkerpool"
+	//ChannelBackend is the backend built on native channels and goroutines
+	ChannelBackend = "channel"
+)
+
+//Backend creates the pool of workers behind a manager pool. maxJobsInQueue is the maximum amount of tasks
+//the manager keeps queued in it and verbose enables its logs, if it has any.
+//The pool is created without workers, the manager adds them when it is started
+type Backend func(maxJobsInQueue int, verbose bool) Descriptor
+
+var backends = struct {
+	mutex    sync.RWMutex
+	backends map[string]Backend
+}{backends: map[string]Backend{
+	GoWorkerPoolBackend: newGoWorkerPool,
+	ChannelBackend:      newChannelPool,
+}}
+
+//RegisterBackend makes backend available to the manager pools under name
+func RegisterBackend(name string, backend Backend) error {
+	if name == "" {
+		return errors.New("backend name cannot be empty")
+	}
+	if backend == nil {
+		return errors.New("backend cannot be nil")
+	}
+	backends.mutex.Lock()
+	defer backends.mutex.Unlock()
+	if _, ok := backends.backends[name]; ok {
+		return errors.New(fmt.Sprintf("a backend with `%s` name already exist", name))
+	}
+	backends.backends[name] = backend
+	return nil
+}
+
+//LookupBackend returns the backend registered under name
+func LookupBackend(name string) (Backend, error) {
+	backends.mutex.RLock()
+	defer backends.mutex.RUnlock()
+	backend, ok := backends.backends[name]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("backend with `%s` name is not registered", name))
+	}
+	return backend, nil
+}
+
+//Backends returns the names of the registered backends, sorted
+func Backends() []string {
+	backends.mutex.RLock()
+	defer backends.mutex.RUnlock()
+	names := make([]string, 0, len(backends.backends))
+	for name := range backends.backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//newGoWorkerPool creates a goworkerpool without workers, with room for the worker operations sharing its queue
+func newGoWorkerPool(maxJobsInQueue int, verbose bool) Descriptor {
+	// goworkerpool signals the tasks and the worker operations (AddWorkers, KillWorkers, SetTotalWorkers) through the
+	// same channel, sized by its maxOperationsInQueue. The manager keeps at most maxJobsInQueue tasks queued, the
+	// other half of the channel lets the workers be resized while the queue is full instead of blocking behind it
+	return &GoWorkerPoolAdapter{Pool: goworkerpool.NewPool(0, maxJobsInQueue*2, verbose)}
+}
+
+func newChannelPool(maxJobsInQueue int, _ bool) Descriptor {
+	return NewChannelPool(maxJobsInQueue)
+}
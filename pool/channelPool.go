@@ -0,0 +1,258 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+//ChannelPool is a Descriptor built on native channels and goroutines: tasks are queued in a buffered channel
+//and each worker is a goroutine taking them in order. It is safe for concurrent use
+type ChannelPool struct {
+	mutex      sync.Mutex
+	tasks      chan interface{}
+	workerFunc func(interface{}) bool
+	//stops holds a channel per worker not asked to exit, closing it makes the worker exit once its current task is over
+	stops []chan struct{}
+	//resumed is closed while the workers are not paused, paused while they are
+	resumed chan struct{}
+	paused  chan struct{}
+	//held are the tasks taken by a worker once paused, they are run before the queued ones once resumed
+	held  []interface{}
+	alive int
+	//gone is closed while no worker is alive
+	gone       chan struct{}
+	inProgress int64
+}
+
+//NewChannelPool creates a ChannelPool without workers queueing up to maxJobsInQueue tasks
+func NewChannelPool(maxJobsInQueue int) *ChannelPool {
+	resumed, gone := make(chan struct{}), make(chan struct{})
+	close(resumed)
+	close(gone)
+	return &ChannelPool{tasks: make(chan interface{}, maxJobsInQueue), resumed: resumed, paused: make(chan struct{}), gone: gone}
+}
+
+//SetWorkerFunc sets the function to be executed by the workers on this pool
+func (definer *ChannelPool) SetWorkerFunc(fn func(interface{}) bool) {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
+	definer.workerFunc = fn
+}
+
+//AddTask queues data for the workers, it fails instead of waiting if the queue is full
+func (definer *ChannelPool) AddTask(data interface{}) error {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
+	if definer.workerFunc == nil {
+		return errors.New("missing worker function")
+	}
+	select {
+	case definer.tasks <- data:
+		return nil
+	default:
+		return errors.New(fmt.Sprintf("queue of %d tasks is full", cap(definer.tasks)))
+	}
+}
+
+//AddWorkers starts amount new workers
+func (definer *ChannelPool) AddWorkers(amount int) error {
+	if amount < 0 {
+		return errors.New("amount of workers to add has to be greater or equal to 0")
+	}
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
+	definer.addWorkers(amount)
+	return nil
+}
+
+//addWorkers implements AddWorkers, it has to be called holding the mutex
+func (definer *ChannelPool) addWorkers(amount int) {
+	if amount > 0 && definer.alive == 0 {
+		definer.gone = make(chan struct{})
+	}
+	for i := 0; i < amount; i++ {
+		stop := make(chan struct{})
+		definer.stops = append(definer.stops, stop)
+		definer.alive++
+		go definer.work(stop)
+	}
+}
+
+//KillWorkers kills the desired amount of workers after they finish their current job
+func (definer *ChannelPool) KillWorkers(amount int) error {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
+	if amount < 0 || amount > len(definer.stops) {
+		return errors.New("cannot kill an amount bigger than the existing workers")
+	}
+	definer.killWorkers(amount)
+	return nil
+}
+
+//killWorkers implements KillWorkers, it has to be called holding the mutex
+func (definer *ChannelPool) killWorkers(amount int) {
+	kept := len(definer.stops) - amount
+	for _, stop := range definer.stops[kept:] {
+		close(stop)
+	}
+	definer.stops = definer.stops[:kept]
+}
+
+//EditWorkersAmount changes the amount of workers on the fly
+func (definer *ChannelPool) EditWorkersAmount(amount int) error {
+	if amount < 0 {
+		return errors.New("amount of workers has to be greater or equal to 0")
+	}
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
+	if current := len(definer.stops); amount > current {
+		definer.addWorkers(amount - current)
+	} else {
+		definer.killWorkers(current - amount)
+	}
+	return nil
+}
+
+//PauseAllWorkers stops the workers from taking tasks, the ones in progress are finished
+func (definer *ChannelPool) PauseAllWorkers() {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
+	select {
+	case <-definer.resumed:
+		definer.resumed = make(chan struct{})
+		close(definer.paused)
+	default:
+	}
+}
+
+//ResumeAllWorkers puts workers to work after being paused
+func (definer *ChannelPool) ResumeAllWorkers() {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
+	select {
+	case <-definer.resumed:
+	default:
+		close(definer.resumed)
+		definer.paused = make(chan struct{})
+	}
+}
+
+//Wait waits while there is at least one worker alive
+func (definer *ChannelPool) Wait() error {
+	return definer.WaitContext(context.Background())
+}
+
+//WaitContext waits while there is at least one worker alive or until ctx is done
+func (definer *ChannelPool) WaitContext(ctx context.Context) error {
+	definer.mutex.Lock()
+	gone := definer.gone
+	definer.mutex.Unlock()
+	select {
+	case <-gone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+//GetTotalWorkers returns the amount of workers alive in the pool, including the ones finishing their task before exiting
+func (definer *ChannelPool) GetTotalWorkers() int {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
+	return definer.alive
+}
+
+//GetTotalWorkersInProgress returns the amount of workers currently processing a task
+func (definer *ChannelPool) GetTotalWorkersInProgress() int {
+	return int(atomic.LoadInt64(&definer.inProgress))
+}
+
+//GetQueuedTasks returns the amount of tasks waiting for a worker to pick them up
+func (definer *ChannelPool) GetQueuedTasks() int {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
+	return len(definer.held) + len(definer.tasks)
+}
+
+//work is the loop of a worker, it takes tasks while they are not paused until stop is closed
+func (definer *ChannelPool) work(stop chan struct{}) {
+	defer definer.exit()
+	for {
+		definer.mutex.Lock()
+		resumed, paused := definer.resumed, definer.paused
+		definer.mutex.Unlock()
+		select {
+		case <-stop:
+			return
+		case <-resumed:
+		}
+		if data, ok := definer.unhold(); ok {
+			definer.run(data)
+			continue
+		}
+		select {
+		case <-stop:
+			return
+		case <-paused:
+			// paused while waiting for a task, it waits to be resumed
+		case data := <-definer.tasks:
+			// the task may have been received as the workers were paused, it is kept for when they are resumed
+			if !definer.hold(data) {
+				definer.run(data)
+			}
+		}
+	}
+}
+
+//hold keeps data aside if the workers are paused and returns whether it did so
+func (definer *ChannelPool) hold(data interface{}) bool {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
+	select {
+	case <-definer.resumed:
+		return false
+	default:
+		definer.held = append(definer.held, data)
+		return true
+	}
+}
+
+//unhold returns the oldest task kept aside by hold, unless the workers are paused
+func (definer *ChannelPool) unhold() (interface{}, bool) {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
+	select {
+	case <-definer.resumed:
+	default:
+		return nil, false
+	}
+	if len(definer.held) == 0 {
+		return nil, false
+	}
+	data := definer.held[0]
+	definer.held = definer.held[1:]
+	return data, true
+}
+
+//run executes the worker function with data
+func (definer *ChannelPool) run(data interface{}) {
+	atomic.AddInt64(&definer.inProgress, 1)
+	defer atomic.AddInt64(&definer.inProgress, -1)
+	definer.mutex.Lock()
+	workerFunc := definer.workerFunc
+	definer.mutex.Unlock()
+	workerFunc(data)
+}
+
+//exit accounts for a worker that is gone
+func (definer *ChannelPool) exit() {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
+	definer.alive--
+	if definer.alive == 0 {
+		close(definer.gone)
+	}
+}
@@ -0,0 +1,115 @@
+package pool
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestChannelPool_Workers(t *testing.T) {
+	channelPool := NewChannelPool(2)
+	if err := channelPool.AddTask("task"); err == nil {
+		t.Errorf("AddTask() without worker function must fail")
+	}
+	done := make(chan interface{}, 3)
+	release := make(chan struct{})
+	channelPool.SetWorkerFunc(func(data interface{}) bool {
+		<-release
+		done <- data
+		return true
+	})
+	channelPool.AddTask(1)
+	channelPool.AddTask(2)
+	if err := channelPool.AddTask(3); err == nil {
+		t.Errorf("AddTask() on a full queue must fail")
+	}
+	if err := channelPool.KillWorkers(1); err == nil {
+		t.Errorf("KillWorkers() of more workers than the existing ones must fail")
+	}
+
+	channelPool.EditWorkersAmount(1)
+	close(release)
+	if data := <-done; data != 1 {
+		t.Errorf("processed %v first, want 1", data)
+	}
+	channelPool.PauseAllWorkers()
+	// the task taken before the pause (if any) is let finish
+	time.Sleep(10 * time.Millisecond)
+	processed := len(done)
+	channelPool.AddTask(4)
+	time.Sleep(20 * time.Millisecond)
+	if len(done) != processed {
+		t.Errorf("processed a task while paused")
+	}
+	channelPool.ResumeAllWorkers()
+	for len(done) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	channelPool.EditWorkersAmount(0)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := channelPool.WaitContext(ctx); err != nil || channelPool.GetTotalWorkers() != 0 {
+		t.Errorf("WaitContext() error = %v, GetTotalWorkers() = %d", err, channelPool.GetTotalWorkers())
+	}
+}
+
+func TestRegisterBackend(t *testing.T) {
+	t.Cleanup(func() {
+		backends.mutex.Lock()
+		defer backends.mutex.Unlock()
+		delete(backends.backends, "test")
+	})
+	tests := []struct {
+		name    string
+		backend string
+		wantErr bool
+	}{
+		{"Returns error if the name is empty", "", true},
+		{"Returns error if the name is taken", ChannelBackend, true},
+		{"Registers the backend", "test", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RegisterBackend(tt.backend, newChannelPool)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RegisterBackend() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := LookupBackend("test"); err != nil {
+		t.Errorf("LookupBackend() error = %v", err)
+	}
+}
+
+func TestChannelPool_PauseAllWorkers(t *testing.T) {
+	// a single thread lets the tasks be handed to the waiting workers before any of them runs
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	channelPool := NewChannelPool(8)
+	done := make(chan interface{}, 8)
+	channelPool.SetWorkerFunc(func(data interface{}) bool {
+		done <- data
+		return true
+	})
+	channelPool.AddWorkers(8)
+	time.Sleep(10 * time.Millisecond)
+	// the workers waiting for a task receive one each, they must not run it once paused
+	for i := 0; i < 8; i++ {
+		channelPool.AddTask(i)
+	}
+	channelPool.PauseAllWorkers()
+	time.Sleep(20 * time.Millisecond)
+	if len(done) != 0 || channelPool.GetQueuedTasks() != 8 {
+		t.Errorf("processed %d tasks once paused, %d queued", len(done), channelPool.GetQueuedTasks())
+	}
+	channelPool.ResumeAllWorkers()
+	for i := 0; i < 8; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("processed %d tasks once resumed, want 8", i)
+		}
+	}
+	channelPool.EditWorkersAmount(0)
+}
@@ -0,0 +1,97 @@
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+//GoWorkerPoolFake is a Descriptor running the worker function synchronously within AddTask,
+//for the tests that need the tasks to be processed without real workers
+type GoWorkerPoolFake struct {
+	mutex        sync.Mutex
+	totalWorkers int
+	workerFunc   func(interface{}) bool
+	//AddTaskError is returned by AddTask instead of running the worker function, if set
+	AddTaskError error
+}
+
+//SetWorkerFunc sets the function run by AddTask
+func (fake *GoWorkerPoolFake) SetWorkerFunc(fn func(interface{}) bool) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.workerFunc = fn
+}
+
+//AddTask runs synchronously the worker function (if any) with the given data, unless AddTaskError is set
+func (fake *GoWorkerPoolFake) AddTask(data interface{}) error {
+	fake.mutex.Lock()
+	workerFunc, err := fake.workerFunc, fake.AddTaskError
+	fake.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if workerFunc != nil {
+		workerFunc(data)
+	}
+	return nil
+}
+
+//AddWorkers adds amount to the workers count
+func (fake *GoWorkerPoolFake) AddWorkers(amount int) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.totalWorkers += amount
+	return nil
+}
+
+//KillWorkers removes amount from the workers count
+func (fake *GoWorkerPoolFake) KillWorkers(amount int) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.totalWorkers -= amount
+	if fake.totalWorkers < 0 {
+		fake.totalWorkers = 0
+	}
+	return nil
+}
+
+//EditWorkersAmount sets the workers count
+func (fake *GoWorkerPoolFake) EditWorkersAmount(workersAmount int) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.totalWorkers = workersAmount
+	return nil
+}
+
+//PauseAllWorkers does nothing, the tasks are run by AddTask
+func (fake *GoWorkerPoolFake) PauseAllWorkers() {}
+
+//ResumeAllWorkers does nothing, the tasks are run by AddTask
+func (fake *GoWorkerPoolFake) ResumeAllWorkers() {}
+
+//Wait returns right away, the tasks are over once AddTask returns
+func (fake *GoWorkerPoolFake) Wait() error {
+	return nil
+}
+
+//WaitContext returns the error of ctx, if any
+func (fake *GoWorkerPoolFake) WaitContext(ctx context.Context) error {
+	return ctx.Err()
+}
+
+//GetTotalWorkers returns the workers count
+func (fake *GoWorkerPoolFake) GetTotalWorkers() int {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	return fake.totalWorkers
+}
+
+//GetTotalWorkersInProgress returns 0, the tasks are run by AddTask
+func (fake *GoWorkerPoolFake) GetTotalWorkersInProgress() int {
+	return 0
+}
+
+//GetQueuedTasks returns 0, the tasks are run by AddTask
+func (fake *GoWorkerPoolFake) GetQueuedTasks() int {
+	return 0
+}
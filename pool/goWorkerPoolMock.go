@@ -1,6 +1,12 @@
 package pool
 
+import (
+	"context"
+	"sync"
+)
+
 type GoWorkerPoolMock struct {
+	mutex                         sync.Mutex
 	totalWorkers                  int
 	SetWorkerFuncHasBeenCalled    bool
 	AddTaskFuncHasBeenCalled      bool
@@ -13,38 +19,75 @@ type GoWorkerPoolMock struct {
 }
 
 func (definer *GoWorkerPoolMock) SetWorkerFunc(fn func(interface{}) bool) {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
 	definer.SetWorkerFuncHasBeenCalled = true
 }
 
 func (definer *GoWorkerPoolMock) AddTask(data interface{}) error {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
 	definer.AddTaskFuncHasBeenCalled = true
 	return nil
 }
 
 func (definer *GoWorkerPoolMock) AddWorkers(amount int) error {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
 	definer.AddWorkersHasBeenCalled = true
 	return nil
 }
 
 func (definer *GoWorkerPoolMock) KillWorkers(amount int) error {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
 	definer.KillWorkersHasBeenCalled = true
 	return nil
 }
 
 func (definer *GoWorkerPoolMock) EditWorkersAmount(workersAmount int) error {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
 	definer.EditWorkersHasBeenCalled = true
 	return nil
 }
 
 func (definer *GoWorkerPoolMock) PauseAllWorkers() {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
 	definer.PauseAllWorkersHasBeenCalled = true
 }
 
 func (definer *GoWorkerPoolMock) ResumeAllWorkers() {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
 	definer.ResumeAllWorkersHasBeenCalled = true
 }
 
 func (definer *GoWorkerPoolMock) Wait() error {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
 	definer.WaitHasBeenCalled = true
 	return nil
 }
+
+func (definer *GoWorkerPoolMock) WaitContext(ctx context.Context) error {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
+	definer.WaitHasBeenCalled = true
+	return ctx.Err()
+}
+
+func (definer *GoWorkerPoolMock) GetTotalWorkers() int {
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
+	return definer.totalWorkers
+}
+
+func (definer *GoWorkerPoolMock) GetTotalWorkersInProgress() int {
+	return 0
+}
+
+func (definer *GoWorkerPoolMock) GetQueuedTasks() int {
+	return 0
+}
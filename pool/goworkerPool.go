@@ -1,23 +1,49 @@
 package pool
 
 import (
+	"context"
 	"errors"
 	"github.com/enriquebris/goworkerpool"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+//waitPollInterval is how often WaitContext checks whether the workers are gone
+const waitPollInterval = 10 * time.Millisecond
 
+//GoWorkerPoolAdapter exposes a goworkerpool.Pool as a Descriptor.
+//The worker function and the pause signal are not synchronized by goworkerpool,
+//so the adapter guards them to be safe for concurrent use
 type GoWorkerPoolAdapter struct {
 	*goworkerpool.Pool
+	mutex sync.RWMutex
+	//pausing serializes the pauses and resumes apart from mutex, a resume blocks until the dispatcher gets to the pause
+	pausing sync.Mutex
+	queued  int64
 }
 
 //SetWorkerFunc sets the function to be executed by the workers on this pool
 func (definer *GoWorkerPoolAdapter) SetWorkerFunc(fn func(interface{})bool) {
-	definer.Pool.SetWorkerFunc(fn)
+	definer.mutex.Lock()
+	defer definer.mutex.Unlock()
+	definer.Pool.SetWorkerFunc(func(data interface{}) bool {
+		atomic.AddInt64(&definer.queued, -1)
+		return fn(data)
+	})
 }
 
 //AddTask adds task to be executed
 func (definer *GoWorkerPoolAdapter) AddTask(data interface{}) error {
-	return definer.Pool.AddTask(data)
+	definer.mutex.RLock()
+	defer definer.mutex.RUnlock()
+	// counted before it is added, a worker could pick it up and uncount it first otherwise
+	atomic.AddInt64(&definer.queued, 1)
+	if err := definer.Pool.AddTask(data); err != nil {
+		atomic.AddInt64(&definer.queued, -1)
+		return err
+	}
+	return nil
 }
 
 //AddWorkers adds workers on the fly to the pool
@@ -40,15 +66,49 @@ func (definer *GoWorkerPoolAdapter) EditWorkersAmount(workersAmount int) error {
 
 //PauseAllWorkers stops the workers from doing any work
 func (definer *GoWorkerPoolAdapter) PauseAllWorkers() {
+	definer.pausing.Lock()
+	defer definer.pausing.Unlock()
 	definer.Pool.PauseAllWorkers()
 }
 
 //ResumeAllWorkers puts workers to work after being paused
 func (definer *GoWorkerPoolAdapter) ResumeAllWorkers() {
+	definer.pausing.Lock()
+	defer definer.pausing.Unlock()
 	definer.Pool.ResumeAllWorkers()
 }
 
 //Wait wait while there is at least one worker doing some work
 func (definer *GoWorkerPoolAdapter) Wait() error{
 	return definer.Pool.Wait()
-}
\ No newline at end of file
+}
+
+//WaitContext waits while there is at least one worker alive or until ctx is done.
+//goworkerpool only supports one Wait at a time, so the amount of workers is polled instead
+func (definer *GoWorkerPoolAdapter) WaitContext(ctx context.Context) error {
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+	for definer.GetTotalWorkers() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+//GetTotalWorkers returns the amount of workers alive in the pool
+func (definer *GoWorkerPoolAdapter) GetTotalWorkers() int {
+	return definer.Pool.GetTotalWorkers()
+}
+
+//GetTotalWorkersInProgress returns the amount of workers currently processing a task
+func (definer *GoWorkerPoolAdapter) GetTotalWorkersInProgress() int {
+	return definer.Pool.GetTotalWorkersInProgress()
+}
+
+//GetQueuedTasks returns the amount of tasks waiting for a worker to pick them up
+func (definer *GoWorkerPoolAdapter) GetQueuedTasks() int {
+	return int(atomic.LoadInt64(&definer.queued))
+}
@@ -1,5 +1,8 @@
 package pool
 
+import "context"
+
+//Descriptor is the set of operations the manager needs from a pool of workers
 type Descriptor interface {
 	SetWorkerFunc(fn func(interface{}) bool)
 	AddTask(data interface{}) error
@@ -9,5 +12,9 @@ type Descriptor interface {
 	PauseAllWorkers()
 	ResumeAllWorkers()
 	Wait() error
+	WaitContext(ctx context.Context) error
+	GetTotalWorkers() int
+	GetTotalWorkersInProgress() int
+	GetQueuedTasks() int
 }
 